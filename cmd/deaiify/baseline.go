@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"deaiify/internal/baseline"
+	"deaiify/internal/git"
+	"deaiify/internal/report"
+)
+
+// Baseline state of a scan. knownFindings holds the findings to leave
+// out, foundFindings collects every finding for --write-baseline, and
+// scannedPaths records the files scanned, for --prune-baseline. Paths in
+// a baseline are relative to baselineDir, the directory it is in, so it
+// matches wherever the scan runs from.
+var (
+	knownFindings *baseline.Matcher
+	foundFindings *baseline.Baseline
+	scannedPaths  map[string]bool
+	baselineDir   string
+)
+
+// defaultBaseline returns the baseline scan reads without --baseline: the
+// one at the top of the repository containing path, or else the one in
+// the current directory
+func defaultBaseline(path string) string {
+	if top, err := git.TopLevel(path); err == nil {
+		return filepath.Join(top, baseline.DefaultFile)
+	}
+	return baseline.DefaultFile
+}
+
+// useBaseline makes baseline paths relative to the directory of the
+// baseline file at path
+func useBaseline(path string) {
+	baselineDir = filepath.Dir(path)
+	if abs, err := filepath.Abs(baselineDir); err == nil {
+		baselineDir = abs
+	}
+}
+
+// baselinePath returns the path of a file as the baseline records it
+func baselinePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		if rel, err := filepath.Rel(baselineDir, abs); err == nil {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// loadBaseline reads the baseline at path. A missing file is only an
+// error if it was named explicitly.
+func loadBaseline(path string, explicit bool) *baseline.Baseline {
+	b, err := baseline.Read(path)
+	if os.IsNotExist(err) && !explicit {
+		return nil
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading baseline: %v\n", err)
+		os.Exit(2)
+	}
+	return b
+}
+
+// checkBaseline marks the findings in a file's report entry that are in
+// the baseline, and collects all of them when a baseline is being written
+func checkBaseline(rf *report.File, sf *scannedFile) {
+	path := baselinePath(sf.file.Path)
+	if scannedPaths != nil {
+		scannedPaths[path] = true
+	}
+	if knownFindings == nil && foundFindings == nil {
+		return
+	}
+
+	// Find symbols with every comment blanked out, so that limiting the
+	// scan to changed lines gives the same fingerprints
+	symbols := make(map[int]string, len(sf.all))
+	for i, symbol := range baseline.Symbols(sf.parser, sf.file.Content, sf.all) {
+		symbols[sf.all[i].Start] = symbol
+	}
+	for i := range rf.Comments {
+		c := &rf.Comments[i]
+		if !c.AILike {
+			continue
+		}
+		e := baseline.NewEntry(path, symbols[sf.comments[i].Start], c.Text)
+		if foundFindings != nil {
+			foundFindings.Add(e)
+		}
+		if knownFindings != nil && knownFindings.Match(e) {
+			rf.MarkBaselined(i)
+		}
+	}
+}
+
+// pruneBaseline rewrites the baseline at path without the findings the
+// scan no longer found
+func pruneBaseline(b *baseline.Baseline, path string) {
+	dropped := b.Prune(knownFindings, func(p string) bool {
+		if scannedPaths[p] {
+			return true
+		}
+		_, err := os.Stat(filepath.Join(baselineDir, filepath.FromSlash(p)))
+		return os.IsNotExist(err)
+	})
+	if err := b.Write(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing baseline: %v\n", err)
+		os.Exit(2)
+	}
+	fmt.Fprintf(os.Stderr, "Removed %d fixed findings from %s, %d left\n", dropped, path, len(b.Findings))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"deaiify/internal/git"
+	"deaiify/internal/report"
+)
+
+// runCommits checks recent commit messages, or with --message-file the
+// message of a commit being made
+func runCommits(args []string) {
+	fs := newFlagSet("commits", "[flags] [repo]",
+		"Checks the recent commit messages of repo (default .) for emoji, AI\n"+
+			"footers and AI-style phrasing. Exits 1 if any are found.")
+	fs.IntVar(&opts.commitCount, "count", 20, "Number of commits to check")
+	fs.IntVar(&opts.commitCount, "commits", 20, "Alias for --count")
+	fs.BoolVar(&opts.verbose, "verbose", false, "Show a detailed log")
+	fs.StringVar(&opts.format, "format", "text", "Output `format`: text, json or sarif")
+	messageFile := fs.String("message-file", "", "Check the commit message in `file` instead, as a commit-msg hook does")
+	path := pathArg(fs, parseArgs(fs, args), ".")
+
+	if *messageFile != "" {
+		if !checkMessageFile(*messageFile) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	startReport()
+	if runCommitScan(path) > 0 {
+		os.Exit(1)
+	}
+}
+
+// checkMessageFile checks a commit message written by git and reports
+// whether it looks fine
+func checkMessageFile(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	w := git.CheckMessage(string(data))
+	if w == nil {
+		return true
+	}
+	fmt.Fprintf(os.Stderr, "deaiify: commit message looks AI-written: %s\n", w.Subject)
+	for _, reason := range w.Reasons {
+		fmt.Fprintf(os.Stderr, "  - %s\n", reason)
+	}
+	return false
+}
+
+// runCommitScan prints or reports the suspicious commits among the last
+// --count commits and returns how many there are
+func runCommitScan(path string) int {
+	if !git.IsGitRepo(path) {
+		fmt.Fprintf(os.Stderr, "Error: %s is not a git repository\n", path)
+		os.Exit(2)
+	}
+
+	fmt.Fprintf(logOut, "Scanning last %d commits for AI patterns...\n\n", opts.commitCount)
+
+	warnings := scanCommits(path)
+	if runReport != nil {
+		addWarnings(warnings)
+		writeReport()
+		return len(warnings)
+	}
+
+	if len(warnings) == 0 {
+		fmt.Println("No AI patterns detected in commits.")
+		return 0
+	}
+
+	fmt.Printf("Found %d suspicious commits:\n\n", len(warnings))
+
+	for _, w := range warnings {
+		fmt.Printf("  %s %s\n", w.Hash, w.Subject)
+		for _, reason := range w.Reasons {
+			fmt.Printf("    - %s\n", reason)
+		}
+		fmt.Println()
+	}
+
+	fmt.Println("Consider rewriting these commits with: git rebase -i")
+	return len(warnings)
+}
+
+// addCommits adds the suspicious commits among the last --commits
+// commits to runReport
+func addCommits(path string) {
+	if !git.IsGitRepo(path) {
+		fmt.Fprintf(os.Stderr, "Error: %s is not a git repository\n", path)
+		os.Exit(2)
+	}
+	addWarnings(scanCommits(path))
+}
+
+func scanCommits(path string) []git.CommitWarning {
+	warnings, err := git.ScanCommits(path, opts.commitCount)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning commits: %v\n", err)
+		os.Exit(2)
+	}
+	return warnings
+}
+
+func addWarnings(warnings []git.CommitWarning) {
+	for _, w := range warnings {
+		runReport.AddCommit(report.Commit{Hash: w.Hash, Subject: w.Subject, Reasons: w.Reasons, Rules: w.Rules})
+	}
+}
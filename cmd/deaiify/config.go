@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"deaiify/internal/config"
+)
+
+// runConfig shows the effective configuration for a path
+func runConfig(args []string) {
+	fs := newFlagSet("config", "--print [path]",
+		fmt.Sprintf("Shows the settings that apply to path (default .) after merging %s files.", config.FileName))
+	printConfig := fs.Bool("print", false, "Print the effective config as JSON")
+	path := pathArg(fs, parseArgs(fs, args), ".")
+	if !*printConfig {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	settings, err := config.NewLoader().For(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+		os.Exit(2)
+	}
+
+	if len(settings.Sources) == 0 {
+		fmt.Fprintf(os.Stderr, "No %s files apply; showing built-in defaults\n", config.FileName)
+	}
+	for _, source := range settings.Sources {
+		fmt.Fprintf(os.Stderr, "From %s\n", source)
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	fmt.Println(string(data))
+}
+
+// runInit writes a starter config file
+func runInit(args []string) {
+	fs := newFlagSet("init", "[flags] [dir]",
+		fmt.Sprintf("Writes a %s to dir (default .) holding the built-in defaults of the\n"+
+			"settings projects most often tune.", config.FileName))
+	force := fs.Bool("force", false, "Overwrite an existing config file")
+	dir := pathArg(fs, parseArgs(fs, args), ".")
+
+	path := filepath.Join(dir, config.FileName)
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if *force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if os.IsExist(err) {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists; use --force to overwrite it\n", path)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	_, err = f.Write(config.Starter())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+		os.Exit(2)
+	}
+	fmt.Printf("Wrote %s\n", path)
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"deaiify/internal/diff"
+	"deaiify/internal/fileio"
+	"deaiify/internal/git"
+	"deaiify/internal/journal"
+	"deaiify/internal/lang"
+	"deaiify/internal/linter"
+	"deaiify/internal/parser"
+	"deaiify/internal/report"
+	"deaiify/internal/transformer"
+	"deaiify/internal/verify"
+	"deaiify/internal/walker"
+)
+
+// runFixCommand rewrites the AI-style comments under a path
+func runFixCommand(args []string) {
+	fs := newFlagSet("fix", "[flags] <path>",
+		"Rewrites AI-style comments in the files under path, adds the odd typo and\n"+
+			"formatting slip, and saves the originals for 'deaiify undo'.")
+	opts.fixFlags(fs)
+	opts.scanFlags(fs, "text")
+	opts.changeFlags(fs)
+	path := pathArg(fs, parseArgs(fs, args), "")
+	runFix(fs, path)
+}
+
+// runFix transforms the files under path with the flags parsed by fs
+func runFix(fs *flag.FlagSet, path string) {
+	startReport()
+	if opts.showDiff {
+		logOut = os.Stderr
+	}
+
+	// Without --seed, pick one and show it so the run can be repeated
+	if !isFlagSet(fs, "seed") {
+		opts.seed = time.Now().UnixNano()
+		fmt.Fprintf(logOut, "Seed: %d\n", opts.seed)
+	}
+	root := journalRoot(path)
+	runJournal = journal.New(root, opts.seed)
+	if runReport != nil {
+		runReport.Seed = opts.seed
+	}
+
+	files, rootSettings := findFiles(path)
+
+	// Detect available linting tools
+	availableTools = linter.DetectTools(lang.Linters(), lang.SyntaxChecks())
+	if opts.verbose {
+		for _, l := range lang.All() {
+			if tool := availableTools.Pick(linter.Prefer(l.Linters, rootSettings.Linters[l.Name])); tool != nil {
+				fmt.Fprintf(logOut, "%s linter: %s\n", l.DisplayName, tool.Name)
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		fmt.Fprintln(logOut, "No supported files found.")
+		writeReport()
+		os.Exit(0)
+	}
+
+	if opts.verbose {
+		fmt.Fprintf(logOut, "Found %d files to process\n\n", len(files))
+	}
+
+	totalFiles := 0
+	totalTransformations := 0
+	rejectedFiles := 0
+
+	for _, file := range files {
+		transformations, err := processFile(file)
+		if errors.Is(err, errRejected) {
+			fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", file.Path, err)
+			rejectedFiles++
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", file.Path, err)
+			continue
+		}
+
+		if transformations > 0 {
+			totalFiles++
+			totalTransformations += transformations
+		}
+	}
+
+	// Summary
+	switch {
+	case opts.dryRun:
+		fmt.Fprintf(logOut, "\n[DRY RUN] Would process %d files, make %d transformations\n", totalFiles, totalTransformations)
+	case !writesFiles():
+		fmt.Fprintf(logOut, "\nFound %d transformations in %d files (files not modified)\n", totalTransformations, totalFiles)
+	default:
+		fmt.Fprintf(logOut, "\nProcessed %d files, made %d transformations\n", totalFiles, totalTransformations)
+	}
+	if rejectedFiles > 0 {
+		fmt.Fprintf(logOut, "%d files left unchanged because their edits would have changed code\n", rejectedFiles)
+	}
+
+	if !runJournal.Empty() {
+		undo := "deaiify undo " + runJournal.ID()
+		if root != journalRoot(".") {
+			undo = fmt.Sprintf("deaiify undo -C %s %s", shellQuote(diffPath(root)), runJournal.ID())
+		}
+		fmt.Fprintf(logOut, "Originals saved as run %s; restore with: %s\n", runJournal.ID(), undo)
+		if opts.staged {
+			fmt.Fprintln(logOut, "The edits are not staged; review them and run git add")
+		}
+	}
+
+	writeReport()
+
+	if opts.patchFile != "" {
+		if err := os.WriteFile(opts.patchFile, []byte(patch.String()), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing patch: %v\n", err)
+			os.Exit(2)
+		}
+		fmt.Fprintf(logOut, "Wrote patch to %s\n", opts.patchFile)
+	}
+
+	// Run linters if requested and files were modified
+	if opts.lint && !opts.noLint && writesFiles() && totalFiles > 0 {
+		runLinters(files)
+	}
+
+	// Show missing tools hint
+	linter.PrintMissingTools(logOut, availableTools, opts.verbose)
+}
+
+// runLinters checks the syntax of each file and runs the linter its
+// config prefers
+func runLinters(files []walker.FileInfo) {
+	fmt.Fprintln(logOut, "\nRunning linters...")
+	lintErrors := 0
+	checkedProjects := make(map[string]bool)
+
+	for _, file := range files {
+		// Check syntax first
+		syntaxResult := linter.CheckSyntax(file.Path, file.Lang.SyntaxCheck)
+		if !syntaxResult.Success {
+			fmt.Fprintf(logOut, "  SYNTAX ERROR in %s:\n    %s\n", file.Path, syntaxResult.Output)
+			lintErrors++
+			continue
+		}
+
+		settings, err := configs.For(file.Path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+			continue
+		}
+		tool := availableTools.Pick(linter.Prefer(file.Lang.Linters, settings.Linters[file.Lang.Name]))
+		// Project-wide tools such as Clippy run once per project
+		if dir := linter.ProjectDir(file.Path, tool); dir != "" {
+			if checkedProjects[tool.Name+"\x00"+dir] {
+				continue
+			}
+			checkedProjects[tool.Name+"\x00"+dir] = true
+		}
+		result := linter.RunLinter(file.Path, tool)
+		if opts.verbose && result.Tool != "none" {
+			fmt.Fprintf(logOut, "  %s: %s\n", file.Path, result.Tool)
+		}
+		if !result.Success {
+			fmt.Fprintf(logOut, "  LINT ERROR in %s:\n    %s\n", file.Path, result.Output)
+			lintErrors++
+		}
+	}
+
+	if lintErrors > 0 {
+		fmt.Fprintf(logOut, "\n%d files had linting issues\n", lintErrors)
+	} else if opts.verbose {
+		fmt.Fprintln(logOut, "All files passed linting")
+	}
+}
+
+func processFile(file walker.FileInfo) (int, error) {
+	sf, err := scanFile(file)
+	if err != nil {
+		return 0, err
+	}
+	if sf.unstaged {
+		return 0, fmt.Errorf("has unstaged changes; stage or stash them first")
+	}
+	if sf.ignored() {
+		if opts.verbose {
+			fmt.Fprintf(logOut, "File: %s (skipped: %s)\n\n", file.Path, parser.IgnoreFileMarker)
+		}
+		if runReport != nil {
+			runReport.Add(sf.skipped())
+		}
+		return 0, nil
+	}
+	f, p, score := sf.file, sf.parser, sf.score
+
+	// Edit the normalized text; f.Encode restores BOM and CRLF
+	originalContent := f.Content
+	currentContent := originalContent
+	t := transformer.ForFile(opts.seed, seedPath(file.Path))
+	sf.settings.Configure(t)
+	if opts.interactive {
+		t.Reviewer = &terminalReviewer{path: file.Path, out: logOut}
+	}
+
+	suppressed := suppressedComments(sf.comments)
+	if opts.verbose && (len(score.GetAIComments()) > 0 || len(suppressed) > 0) {
+		fmt.Fprintf(logOut, "File: %s (AI score: %.2f)\n", file.Path, score.Score)
+		for _, c := range suppressed {
+			fmt.Fprintf(logOut, "  Line %d: suppressed by %s\n", c.LineNumber, c.Suppressed)
+		}
+	}
+
+	var allResults, commentResults []transformer.TransformResult
+
+	// Transform AI-detected comments
+	aiComments := score.GetAIComments()
+	if len(aiComments) > 0 {
+		currentContent, commentResults = t.TransformComments(currentContent, p, aiComments)
+		allResults = append(allResults, commentResults...)
+	}
+
+	// Inject typos in remaining comments (re-parse after transforms)
+	candidates := p.Parse(currentContent).Comments
+	if changes != nil {
+		candidates = onChangedLines(candidates, sf.lines, diff.LineMap(originalContent, currentContent))
+	}
+	newContent, typoResults := t.InjectTypos(currentContent, candidates, p)
+	currentContent = newContent
+	allResults = append(allResults, typoResults...)
+
+	// Apply structural changes, which are not limited to changed lines
+	var structResults []transformer.StructureTransformResult
+	if file.Lang.Structure != nil && changes == nil {
+		currentContent, structResults = file.Lang.Structure(t, currentContent)
+	}
+
+	transformCount := len(allResults) + len(structResults)
+
+	var rf report.File
+	if runReport != nil {
+		rf = report.NewFile(diffPath(file.Path), file.Lang.Name, originalContent, p, score, commentResults, typoResults)
+		rf.Transformations = transformCount
+	}
+
+	// Refuse any edit that touched code rather than comments
+	if err := verify.Equivalent(p, originalContent, currentContent, file.Lang.Verify); err != nil {
+		err = fmt.Errorf("%w: %v", errRejected, err)
+		if runReport != nil {
+			rf.Reject(err.Error())
+			runReport.Add(rf)
+		}
+		return 0, err
+	}
+	if runReport != nil {
+		runReport.Add(rf)
+	}
+
+	// Print verbose output
+	if opts.verbose {
+		for _, r := range allResults {
+			fmt.Fprintf(logOut, "  Line %d: %s\n", r.LineNumber, r.Action)
+			if r.Action != "removed" && r.Replacement != "" {
+				fmt.Fprintf(logOut, "    -> %s\n", r.Replacement)
+			}
+		}
+		for _, r := range structResults {
+			fmt.Fprintf(logOut, "  Line %d: %s\n", r.LineNumber, r.Description)
+		}
+		if transformCount > 0 || len(suppressed) > 0 {
+			fmt.Fprintln(logOut)
+		}
+	}
+
+	updated := f.Encode(currentContent)
+
+	if opts.showDiff || opts.patchFile != "" {
+		if !f.IsText() {
+			// A text diff of the decoded content would not apply to the
+			// bytes on disk
+			if currentContent != originalContent {
+				fmt.Fprintf(logOut, "File: %s (skipped: not UTF-8, patch would not apply)\n", file.Path)
+			}
+		} else {
+			// Diff the bytes on disk so the patch applies to CRLF and BOM files
+			d := diff.Unified(diffPath(file.Path), string(f.Raw), string(updated))
+			if opts.showDiff {
+				fmt.Print(d)
+			}
+			patch.WriteString(d)
+		}
+	}
+
+	// Write if not previewing and there are changes
+	if writesFiles() && currentContent != originalContent {
+		edits := journalEdits(allResults, structResults)
+		if err := writeFile(f, updated, edits); err != nil {
+			return 0, err
+		}
+	}
+
+	return transformCount, nil
+}
+
+// repoTops caches the git top level of each directory seedPath has seen,
+// "" outside a repository
+var repoTops = make(map[string]string)
+
+// seedPath returns the path a file's edits are seeded with: relative to
+// the top of its git repository, or absolute outside git. It does not
+// depend on the path the run was given, so fixing a file alone repeats the
+// edits it got as part of its directory.
+func seedPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	dir := filepath.Dir(abs)
+	top, ok := repoTops[dir]
+	if !ok {
+		top, _ = git.TopLevel(dir)
+		repoTops[dir] = top
+	}
+	if top != "" {
+		if real, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = real
+		}
+		if rel, err := filepath.Rel(top, abs); err == nil {
+			return rel
+		}
+	}
+	return abs
+}
+
+// suppressedComments returns the comments covered by suppression markers
+func suppressedComments(comments []parser.Comment) []parser.Comment {
+	var out []parser.Comment
+	for _, c := range comments {
+		if c.Suppressed != "" {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// writeFile replaces a file's contents after backing it up in the run
+// journal. It refuses if the file no longer holds what was read.
+func writeFile(f *fileio.File, updated []byte, edits []journal.Edit) error {
+	current, err := os.ReadFile(f.Path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(current, f.Raw) {
+		return fmt.Errorf("file changed on disk while it was being processed; not writing")
+	}
+
+	if err := runJournal.Record(f.Path, f.Raw, updated, f.Mode, edits); err != nil {
+		return fmt.Errorf("backing up: %w", err)
+	}
+	return fileio.WriteAtomic(f.Path, updated, f.Mode)
+}
+
+// journalEdits converts a file's transformation results for the journal
+func journalEdits(results []transformer.TransformResult, structResults []transformer.StructureTransformResult) []journal.Edit {
+	var edits []journal.Edit
+	for _, r := range results {
+		if r.Action == "kept" {
+			continue
+		}
+		edits = append(edits, journal.Edit{
+			Line:        r.LineNumber,
+			Action:      r.Action,
+			Original:    r.Original,
+			Replacement: r.Replacement,
+		})
+	}
+	for _, r := range structResults {
+		edits = append(edits, journal.Edit{Line: r.LineNumber, Action: r.Description})
+	}
+	return edits
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"deaiify/internal/git"
+)
+
+// hookMarker identifies hooks written by deaiify, which are replaced
+// without --force
+const hookMarker = "installed by 'deaiify hook install'"
+
+// hookCommands holds the deaiify command each hook runs. Git runs hooks
+// from the top of the working tree, and passes commit-msg the path of the
+// message file.
+var hookCommands = map[string]string{
+	"commit-msg": `commits --message-file "$1"`,
+	"pre-commit": "scan --staged .",
+}
+
+// hookOrder is the order hooks are installed and listed in
+var hookOrder = []string{"commit-msg", "pre-commit"}
+
+// runHook manages the git hooks that run deaiify
+func runHook(args []string) {
+	fs := newFlagSet("hook", "install [flags] [repo]",
+		"Installs git hooks in repo (default .), in core.hooksPath if it is set:\n"+
+			"  commit-msg  checks the message being committed for emoji, AI footers\n"+
+			"              and AI-style phrasing\n"+
+			"  pre-commit  checks the comments on staged lines, like 'scan --staged'\n"+
+			"In block mode a finding stops the commit; skip the hooks for one commit\n"+
+			"with 'git commit --no-verify'. In warn mode findings are only shown.")
+	force := fs.Bool("force", false, "Replace existing hooks not written by deaiify")
+	mode := fs.String("mode", "block", "What a finding does: `block` the commit or warn")
+	only := fs.String("hooks", strings.Join(hookOrder, ","), "Comma-separated `list` of hooks to install")
+	positional := parseArgs(fs, args)
+	if len(positional) == 0 || positional[0] != "install" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	repo := pathArg(fs, positional[1:], ".")
+
+	if *mode != "block" && *mode != "warn" {
+		fmt.Fprintf(os.Stderr, "Error: unknown mode %q (want block or warn)\n", *mode)
+		os.Exit(2)
+	}
+	var names []string
+	for _, name := range strings.Split(*only, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := hookCommands[name]; !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown hook %q (want %s)\n", name, strings.Join(hookOrder, " or "))
+			os.Exit(2)
+		}
+		names = append(names, name)
+	}
+
+	dir, err := git.HooksDir(repo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "deaiify"
+	}
+
+	// Check every hook before writing any, so a refusal leaves none half
+	// installed
+	for _, name := range names {
+		if err := checkHook(filepath.Join(dir, name), *force); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := writeHook(path, hookScript(name, exe, *mode)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		fmt.Printf("Installed %s (%s mode)\n", path, *mode)
+	}
+}
+
+// hookScript returns the script of a hook that runs the deaiify binary
+// at exe. Output is only shown when there are findings, so clean commits
+// stay quiet.
+func hookScript(name, exe, mode string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#!/bin/sh\n# deaiify %s hook, %s mode, %s\n", name, mode, hookMarker)
+	fmt.Fprintf(&sb, "out=$(%s %s 2>&1) && exit 0\n", shellQuote(exe), hookCommands[name])
+	sb.WriteString("printf '%s\\n' \"$out\" >&2\n")
+	if mode == "warn" {
+		sb.WriteString("echo \"deaiify: committing anyway (warn mode)\" >&2\nexit 0\n")
+	} else {
+		sb.WriteString("echo \"deaiify: commit stopped; fix the findings or use git commit --no-verify\" >&2\nexit 1\n")
+	}
+	return sb.String()
+}
+
+// checkHook refuses to replace a hook deaiify did not write unless force
+// is set
+func checkHook(path string, force bool) error {
+	existing, err := os.ReadFile(path)
+	if err == nil && !force && !bytes.Contains(existing, []byte(hookMarker)) {
+		return fmt.Errorf("%s already exists; use --force to replace it", path)
+	}
+	return nil
+}
+
+// writeHook writes an executable hook script
+func writeHook(path, script string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		return err
+	}
+	// WriteFile leaves the mode of an existing file alone
+	return os.Chmod(path, 0755)
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
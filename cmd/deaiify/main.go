@@ -1,263 +1,345 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
-	"deaiify/internal/detector"
+	"deaiify/internal/config"
 	"deaiify/internal/git"
+	"deaiify/internal/journal"
+	"deaiify/internal/lang"
 	"deaiify/internal/linter"
 	"deaiify/internal/parser"
-	"deaiify/internal/transformer"
-	"deaiify/internal/walker"
+	"deaiify/internal/report"
 )
 
-var (
-	dryRun      = flag.Bool("dry-run", false, "Show what would change without modifying files")
-	verbose     = flag.Bool("verbose", false, "Show detailed transformation log")
-	scanCommits = flag.Bool("scan-commits", false, "Scan git commits for AI patterns")
-	commitCount = flag.Int("commits", 20, "Number of commits to scan (default 20)")
-	noLint      = flag.Bool("no-lint", false, "Skip running linters after transformation")
-	lint        = flag.Bool("lint", false, "Run linters after transformation (auto-detected)")
-)
+// options holds the flags of the running command. Each command binds the
+// ones it accepts to its own FlagSet.
+type options struct {
+	dryRun      bool
+	verbose     bool
+	noLint      bool
+	lint        bool
+	showDiff    bool
+	patchFile   string
+	interactive bool
+	seed        int64
+	format      string
+	commitCount int
+	since       string
+	staged      bool
+}
 
-var availableTools linter.AvailableTools
+var opts = options{format: "text"}
+
+// fixFlags binds the flags that control how files are edited
+func (o *options) fixFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.dryRun, "dry-run", false, "Show what would change without modifying files")
+	fs.BoolVar(&o.lint, "lint", false, "Run linters after transformation (auto-detected)")
+	fs.BoolVar(&o.noLint, "no-lint", false, "Skip running linters after transformation")
+	fs.BoolVar(&o.showDiff, "diff", false, "Print a unified diff of each file instead of modifying it")
+	fs.StringVar(&o.patchFile, "patch", "", "Write all edits to a patch `file` instead of modifying files")
+	fs.BoolVar(&o.interactive, "i", false, "Review each edit interactively before applying it")
+	fs.Int64Var(&o.seed, "seed", 0, "Seed for random choices, to reproduce a run (default random)")
+}
 
-func main() {
-	flag.Parse()
+// scanFlags binds the flags shared by every command that reads comments
+func (o *options) scanFlags(fs *flag.FlagSet, format string) {
+	fs.BoolVar(&o.verbose, "verbose", false, "Show a detailed log")
+	fs.StringVar(&o.format, "format", format, "Output `format`: text, json or sarif")
+	fs.Var(&directives, "directive", "Protect comments matching `lang:pattern` (repeatable, lang * for all)")
+}
 
-	// Handle --scan-commits mode
-	if *scanCommits {
-		path := "."
-		if flag.NArg() > 0 {
-			path = flag.Arg(0)
-		}
-		runCommitScan(path)
-		return
-	}
+// changeFlags binds the flags that limit a run to lines changed in git
+func (o *options) changeFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.since, "since", "", "Only look at comments on lines changed since the branch left `ref`")
+	fs.BoolVar(&o.staged, "staged", false, "Only look at comments on lines staged for commit")
+}
 
-	if flag.NArg() == 0 {
-		fmt.Println("Usage: deaiify <path> [options]")
-		fmt.Println("\nTransforms AI-generated code to appear more human-written.")
-		fmt.Println("\nOptions:")
-		fmt.Println("  --dry-run       Show what would change without modifying files")
-		fmt.Println("  --verbose       Show detailed transformation log")
-		fmt.Println("  --lint          Run linters after transformation")
-		fmt.Println("  --no-lint       Skip linters even if available")
-		fmt.Println("  --scan-commits  Scan git commits for AI patterns")
-		fmt.Println("  --commits N     Number of commits to scan (default 20)")
-		os.Exit(1)
-	}
+// directiveFlags collects repeated --directive lang:pattern flags
+type directiveFlags []string
 
-	path := flag.Arg(0)
+func (d *directiveFlags) String() string {
+	return strings.Join(*d, ", ")
+}
 
-	// Detect available linting tools
-	availableTools = linter.DetectTools()
-	if *verbose {
-		if availableTools.JSLinter != nil {
-			fmt.Printf("JS linter: %s\n", availableTools.JSLinter.Name)
-		}
-		if availableTools.PyLinter != nil {
-			fmt.Printf("Python linter: %s\n", availableTools.PyLinter.Name)
-		}
-		if availableTools.GoLinter != nil {
-			fmt.Printf("Go linter: %s\n", availableTools.GoLinter.Name)
+func (d *directiveFlags) Set(value string) error {
+	key, expr, ok := strings.Cut(value, ":")
+	if !ok || key == "" || expr == "" {
+		return fmt.Errorf("expected lang:pattern, got %q", value)
+	}
+	if key != parser.AllLanguages && lang.ByName(key) == nil {
+		var keys []string
+		for _, l := range lang.All() {
+			keys = append(keys, l.Name)
 		}
+		return fmt.Errorf("unknown language %q (want %s or %s)", key, strings.Join(keys, ", "), parser.AllLanguages)
 	}
-
-	// Validate path exists
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		fmt.Fprintf(os.Stderr, "Error: path does not exist: %s\n", path)
-		os.Exit(1)
+	if err := parser.RegisterDirective(key, expr); err != nil {
+		return err
 	}
+	*d = append(*d, value)
+	return nil
+}
 
-	// Walk the path to find files
-	files, err := walker.Walk(path)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error walking path: %v\n", err)
-		os.Exit(1)
-	}
+var directives directiveFlags
 
-	if len(files) == 0 {
-		fmt.Println("No supported files found.")
-		os.Exit(0)
-	}
+var availableTools linter.AvailableTools
 
-	if *verbose {
-		fmt.Printf("Found %d files to process\n\n", len(files))
-	}
+// changes limits a run to the lines changed in git; nil means every line
+var changes git.Changes
 
-	totalFiles := 0
-	totalTransformations := 0
+// configs resolves the .deaiify.json settings for each file
+var configs *config.Loader
 
-	for _, file := range files {
-		transformations, err := processFile(file)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", file.Path, err)
-			continue
-		}
+// runJournal backs up every file this run modifies
+var runJournal *journal.Journal
 
-		if transformations > 0 {
-			totalFiles++
-			totalTransformations += transformations
-		}
-	}
+// logOut receives progress and summary output. It is stderr while diffs
+// go to stdout, so they can be piped straight into a file.
+var logOut io.Writer = os.Stdout
 
-	// Summary
-	if *dryRun {
-		fmt.Printf("\n[DRY RUN] Would process %d files, make %d transformations\n", totalFiles, totalTransformations)
-	} else {
-		fmt.Printf("\nProcessed %d files, made %d transformations\n", totalFiles, totalTransformations)
-	}
+// reportOut receives the report for --format json and sarif
+var reportOut io.Writer = os.Stdout
 
-	// Run linters if requested and not dry-run
-	if *lint && !*noLint && !*dryRun && totalFiles > 0 {
-		fmt.Println("\nRunning linters...")
-		lintErrors := 0
-
-		for _, file := range files {
-			isPython := file.IsPython()
-			isGo := file.IsGo()
-
-			// Check syntax first
-			syntaxResult := linter.CheckSyntax(file.Path, isPython, isGo)
-			if !syntaxResult.Success {
-				fmt.Printf("  SYNTAX ERROR in %s:\n    %s\n", file.Path, syntaxResult.Output)
-				lintErrors++
-				continue
-			}
+// patch collects the diffs written by --patch
+var patch strings.Builder
 
-			// Run linter
-			result := linter.RunLinter(file.Path, isPython, isGo, availableTools)
-			if *verbose && result.Tool != "none" {
-				fmt.Printf("  %s: %s\n", file.Path, result.Tool)
-			}
-			if !result.Success {
-				fmt.Printf("  LINT ERROR in %s:\n    %s\n", file.Path, result.Output)
-				lintErrors++
-			}
-		}
+// runReport collects per-file results for --format json and sarif
+var runReport *report.Report
 
-		if lintErrors > 0 {
-			fmt.Printf("\n%d files had linting issues\n", lintErrors)
-		} else if *verbose {
-			fmt.Println("All files passed linting")
-		}
-	}
+// errRejected marks files whose edits failed token verification
+var errRejected = errors.New("edits rejected")
 
-	// Show missing tools hint
-	linter.PrintMissingTools(availableTools, *verbose)
+// command is a deaiify subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string)
 }
 
-func processFile(file walker.FileInfo) (int, error) {
-	content, err := os.ReadFile(file.Path)
-	if err != nil {
-		return 0, err
+var commands []command
+
+func init() {
+	commands = []command{
+		{"scan", "Report AI-style comments without changing files", runScan},
+		{"fix", "Rewrite AI-style comments", runFixCommand},
+		{"report", "Write a JSON or SARIF report of comments and commits", runReportCommand},
+		{"commits", "Check commit messages for AI patterns", runCommits},
+		{"init", "Write a starter " + config.FileName, runInit},
+		{"config", "Show the settings that apply to a path", runConfig},
+		{"undo", "Restore the files changed by a run", runUndo},
+		{"hook", "Install git hooks", runHook},
+		{"help", "Show help for a command", runHelp},
 	}
+}
 
-	originalContent := string(content)
-	currentContent := originalContent
-
-	// Select parser
-	var p parser.Parser
-	isPython := file.IsPython()
-	isGo := file.IsGo()
-	if isPython {
-		p = parser.NewPythonParser()
-	} else if isGo {
-		p = parser.NewGoParser()
-	} else {
-		p = parser.NewJavaScriptParser()
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
 	}
+	return nil
+}
 
-	// Parse comments
-	result := p.Parse(currentContent)
-
-	// Score comments
-	score := detector.ScoreFile(file.Path, result.Comments)
-
-	if *verbose && len(score.GetAIComments()) > 0 {
-		fmt.Printf("File: %s (AI score: %.2f)\n", file.Path, score.Score)
+func main() {
+	args := os.Args[1:]
+	if len(args) > 0 {
+		if c := findCommand(args[0]); c != nil {
+			c.run(args[1:])
+			return
+		}
+		switch args[0] {
+		case "-h", "-help", "--help":
+			usage(os.Stdout)
+			return
+		}
 	}
+	runLegacy(args)
+}
 
-	var allResults []transformer.TransformResult
-
-	// Transform AI-detected comments
-	aiComments := score.GetAIComments()
-	if len(aiComments) > 0 {
-		newContent, results := transformer.TransformComments(currentContent, p, aiComments, isPython)
-		currentContent = newContent
-		allResults = append(allResults, results...)
+// usage prints the list of commands
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: deaiify <command> [flags] [path]")
+	fmt.Fprintln(w, "\nTransforms AI-generated code to appear more human-written.")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", c.name, c.summary)
 	}
+	fmt.Fprintln(w, "\nRun 'deaiify help <command>' for a command's flags.")
+	fmt.Fprintln(w, "'deaiify [flags] <path>' is the same as 'deaiify fix'; write a path named")
+	fmt.Fprintln(w, "like a command as ./name.")
+}
 
-	// Inject typos in remaining comments (re-parse after transforms)
-	result = p.Parse(currentContent)
-	newContent, typoResults := transformer.InjectTypos(currentContent, result.Comments, p)
-	currentContent = newContent
-	allResults = append(allResults, typoResults...)
+// runHelp shows the help of a command
+func runHelp(args []string) {
+	if len(args) == 0 {
+		usage(os.Stdout)
+		return
+	}
+	c := findCommand(args[0])
+	if c == nil || c.name == "help" {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", args[0])
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	c.run([]string{"-h"})
+}
 
-	// Apply structural changes
-	newContent, structResults := transformer.TransformStructure(currentContent, !isPython)
-	currentContent = newContent
+// runLegacy handles the flag-only command line of earlier versions:
+// "deaiify [flags] <path>" fixes files and --scan-commits checks commits
+func runLegacy(args []string) {
+	fs := flag.NewFlagSet("deaiify", flag.ExitOnError)
+	opts.fixFlags(fs)
+	opts.scanFlags(fs, "text")
+	opts.changeFlags(fs)
+	scanCommits := fs.Bool("scan-commits", false, "Scan git commits for AI patterns, like the commits command")
+	fs.IntVar(&opts.commitCount, "commits", 20, "Number of commits to scan")
+	fs.Usage = func() {
+		usage(fs.Output())
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+	paths := parseArgs(fs, args)
 
-	transformCount := len(allResults) + len(structResults)
+	if *scanCommits {
+		startReport()
+		runCommitScan(pathArg(fs, paths, "."))
+		return
+	}
+	if len(paths) == 0 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	runFix(fs, pathArg(fs, paths, ""))
+}
 
-	// Print verbose output
-	if *verbose {
-		for _, r := range allResults {
-			fmt.Printf("  Line %d: %s\n", r.LineNumber, r.Action)
-			if r.Action != "removed" && r.Replacement != "" {
-				fmt.Printf("    -> %s\n", r.Replacement)
-			}
+// newFlagSet creates the FlagSet of a command, with help made of its
+// synopsis, a description and the flags
+func newFlagSet(name, synopsis, about string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: deaiify %s %s\n\n%s\n", name, synopsis, about)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(out, "\nFlags:")
+			fs.PrintDefaults()
 		}
-		for _, r := range structResults {
-			fmt.Printf("  Line %d: %s\n", r.LineNumber, r.Description)
+	}
+	return fs
+}
+
+// parseArgs parses flags anywhere among args, so "deaiify fix ./src
+// --dry-run" works like "deaiify fix --dry-run ./src", and returns the
+// positional arguments. Everything after "--" is positional.
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional
 		}
-		if transformCount > 0 {
-			fmt.Println()
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(positional, rest...)
 		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
 	}
+}
 
-	// Write if not dry run and there are changes
-	if !*dryRun && currentContent != originalContent {
-		err = os.WriteFile(file.Path, []byte(currentContent), 0644)
-		if err != nil {
-			return 0, err
-		}
+// pathArg returns the only positional argument, or def if there is none.
+// Anything else is a usage error.
+func pathArg(fs *flag.FlagSet, paths []string, def string) string {
+	switch {
+	case len(paths) == 1:
+		return paths[0]
+	case len(paths) == 0 && def != "":
+		return def
 	}
+	fs.Usage()
+	os.Exit(2)
+	return ""
+}
 
-	return transformCount, nil
+// isFlagSet reports whether the named flag was given on the command line
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
 
-func runCommitScan(path string) {
-	if !git.IsGitRepo(path) {
-		fmt.Fprintf(os.Stderr, "Error: %s is not a git repository\n", path)
-		os.Exit(1)
+// checkFormat validates --format against the other output flags
+func checkFormat() error {
+	switch opts.format {
+	case "text":
+		return nil
+	case "json", "sarif":
+		if opts.showDiff {
+			return fmt.Errorf("--diff and --format %s both write to stdout", opts.format)
+		}
+		return nil
 	}
+	return fmt.Errorf("unknown format %q (want text, json or sarif)", opts.format)
+}
 
-	fmt.Printf("Scanning last %d commits for AI patterns...\n\n", *commitCount)
-
-	warnings, err := git.ScanCommits(path, *commitCount)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error scanning commits: %v\n", err)
-		os.Exit(1)
+// startReport validates --format and, unless it is text, starts
+// collecting the report and moves progress output to stderr
+func startReport() {
+	if err := checkFormat(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if opts.format != "text" {
+		logOut = os.Stderr
+		runReport = report.New(opts.seed)
 	}
+}
 
-	if len(warnings) == 0 {
-		fmt.Println("No AI patterns detected in commits.")
+// writeReport prints the collected report in the --format format
+func writeReport() {
+	var err error
+	switch {
+	case runReport == nil:
 		return
+	case opts.format == "sarif":
+		err = runReport.WriteSARIF(reportOut, ".")
+	default:
+		err = runReport.Write(reportOut)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(2)
 	}
+}
 
-	fmt.Printf("Found %d suspicious commits:\n\n", len(warnings))
+// writesFiles reports whether edits go to the files themselves rather
+// than to a preview, diff or patch
+func writesFiles() bool {
+	return !opts.dryRun && !opts.showDiff && opts.patchFile == ""
+}
 
-	for _, w := range warnings {
-		fmt.Printf("  %s %s\n", w.Hash, w.Subject)
-		for _, reason := range w.Reasons {
-			fmt.Printf("    - %s\n", reason)
+// diffPath returns path relative to the working directory with forward
+// slashes, as git apply expects
+func diffPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, abs); err == nil {
+				path = rel
+			}
 		}
-		fmt.Println()
 	}
-
-	fmt.Println("Consider rewriting these commits with: git rebase -i")
+	return filepath.ToSlash(path)
 }
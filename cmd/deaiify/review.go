@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"deaiify/internal/diff"
+	"deaiify/internal/transformer"
+)
+
+// stdin is shared by every file's reviewer so buffered input isn't lost
+var stdin = bufio.NewReader(os.Stdin)
+
+// terminalReviewer asks the user about each edit, like git add -p
+type terminalReviewer struct {
+	path string
+	out  io.Writer
+}
+
+func (r *terminalReviewer) Review(e transformer.Edit) transformer.Verdict {
+	fmt.Fprintf(r.out, "\n%s:%d: %s\n", r.path, e.LineNumber, e.Description)
+	fmt.Fprint(r.out, diff.Hunks(e.Before, e.After))
+
+	options := "y,n,q,?"
+	if e.Editable {
+		options = "y,n,e,q,?"
+	}
+
+	for {
+		fmt.Fprintf(r.out, "Apply this edit [%s]? ", options)
+		answer, err := stdin.ReadString('\n')
+		if err != nil && answer == "" {
+			// No more input: leave the rest of the file alone
+			fmt.Fprintln(r.out)
+			return transformer.Verdict{Decision: transformer.SkipFile}
+		}
+
+		switch strings.TrimSpace(answer) {
+		case "y":
+			return transformer.Verdict{Decision: transformer.Accept}
+		case "n":
+			return transformer.Verdict{Decision: transformer.Reject}
+		case "q":
+			return transformer.Verdict{Decision: transformer.SkipFile}
+		case "e":
+			if !e.Editable {
+				break
+			}
+			fmt.Fprint(r.out, "Comment text (empty to remove): ")
+			text, _ := stdin.ReadString('\n')
+			return transformer.Verdict{Decision: transformer.Replace, Text: strings.TrimSpace(text)}
+		}
+
+		fmt.Fprintln(r.out, "y - apply this edit")
+		fmt.Fprintln(r.out, "n - do not apply this edit")
+		if e.Editable {
+			fmt.Fprintln(r.out, "e - write the comment text yourself")
+		}
+		fmt.Fprintln(r.out, "q - do not apply this or any remaining edit in the file")
+		fmt.Fprintln(r.out, "? - print help")
+	}
+}
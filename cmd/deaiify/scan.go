@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"deaiify/internal/baseline"
+	"deaiify/internal/config"
+	"deaiify/internal/detector"
+	"deaiify/internal/fileio"
+	"deaiify/internal/git"
+	"deaiify/internal/parser"
+	"deaiify/internal/report"
+	"deaiify/internal/walker"
+)
+
+// runScan reports the AI-style comments under a path without editing
+// anything. It exits 1 when they exceed the thresholds, so it can fail a
+// CI job.
+func runScan(args []string) {
+	fs := newFlagSet("scan", "[flags] [path]",
+		"Reports AI-style comments in the files under path (default .) without\n"+
+			"changing them. Exits 1 if any are found, or with --max-score or\n"+
+			"--max-flagged if those limits are exceeded, and 2 on errors. Findings\n"+
+			"listed in the baseline file are left out.")
+	opts.scanFlags(fs, "text")
+	opts.changeFlags(fs)
+	maxScore := fs.Float64("max-score", 0, "Fail if any file's score, or the score of all files together, is above `score` (0 to 1)")
+	maxFlagged := fs.Int("max-flagged", 0, "Fail if more than `n` comments are AI-like in total")
+	baselineFile := fs.String("baseline", "", "Leave out the findings listed in `file` (default "+baseline.DefaultFile+" at the top of the repository, if it exists)")
+	writeBaseline := fs.String("write-baseline", "", "Record the current findings in `file` and exit")
+	prune := fs.Bool("prune-baseline", false, "Remove findings that are gone from the --baseline file")
+	path := pathArg(fs, parseArgs(fs, args), ".")
+
+	// Without limits any finding fails; --max-score alone allows any number
+	g := gate{maxScore: -1}
+	if isFlagSet(fs, "max-score") {
+		if *maxScore < 0 || *maxScore > 1 {
+			fmt.Fprintln(os.Stderr, "Error: --max-score must be between 0 and 1")
+			os.Exit(2)
+		}
+		g.maxScore = *maxScore
+		g.maxFlagged = -1
+	}
+	if isFlagSet(fs, "max-flagged") {
+		if *maxFlagged < 0 {
+			fmt.Fprintln(os.Stderr, "Error: --max-flagged must not be negative")
+			os.Exit(2)
+		}
+		g.maxFlagged = *maxFlagged
+	}
+
+	if *writeBaseline != "" {
+		useBaseline(*writeBaseline)
+		foundFindings = baseline.New()
+		if failed := scanTree(path); failed > 0 {
+			fmt.Fprintf(os.Stderr, "Error: %d files could not be read; baseline not written\n", failed)
+			os.Exit(2)
+		}
+		if err := foundFindings.Write(*writeBaseline); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing baseline: %v\n", err)
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d findings to %s\n", len(foundFindings.Findings), *writeBaseline)
+		return
+	}
+
+	if *baselineFile == "" {
+		*baselineFile = defaultBaseline(path)
+	}
+	known := loadBaseline(*baselineFile, isFlagSet(fs, "baseline") || *prune)
+	if known != nil {
+		useBaseline(*baselineFile)
+		knownFindings = known.Matcher()
+		scannedPaths = make(map[string]bool)
+	}
+
+	startReport()
+	failed := scanTree(path)
+	if *prune {
+		if failed > 0 {
+			fmt.Fprintf(os.Stderr, "Error: %d files could not be read; baseline not pruned\n", failed)
+			os.Exit(2)
+		}
+		pruneBaseline(known, *baselineFile)
+	}
+	if opts.format == "text" {
+		printFindings()
+	} else {
+		writeReport()
+	}
+
+	if failed > 0 {
+		os.Exit(2)
+	}
+	if exceeded := g.check(runReport); len(exceeded) > 0 {
+		for _, e := range exceeded {
+			fmt.Fprintf(os.Stderr, "Limit exceeded: %s\n", e)
+		}
+		os.Exit(1)
+	}
+}
+
+// gate holds the limits a scan must stay within
+type gate struct {
+	maxScore   float64 // For each file and all files together; negative for none
+	maxFlagged int     // AI-like comments in all files; negative for none
+}
+
+// check returns a description of every limit r exceeds. Baselined
+// comments count for neither scores nor findings.
+func (g gate) check(r *report.Report) []string {
+	var exceeded []string
+	if g.maxScore >= 0 {
+		for _, f := range r.Files {
+			if f.TotalComments > f.Baselined && f.Score > g.maxScore {
+				exceeded = append(exceeded, fmt.Sprintf("%s scores %.2f, above %.2f", f.Path, f.Score, g.maxScore))
+			}
+		}
+		if r.Summary.Score > g.maxScore {
+			exceeded = append(exceeded, fmt.Sprintf("all files score %.2f, above %.2f", r.Summary.Score, g.maxScore))
+		}
+	}
+	if flagged := r.Summary.AIComments - r.Summary.Baselined; g.maxFlagged >= 0 && flagged > g.maxFlagged {
+		exceeded = append(exceeded, fmt.Sprintf("%d AI-like comments, more than %d", flagged, g.maxFlagged))
+	}
+	return exceeded
+}
+
+// runReportCommand writes a machine-readable report of the comments under
+// a path and, optionally, of recent commits
+func runReportCommand(args []string) {
+	fs := newFlagSet("report", "[flags] [path]",
+		"Writes a report of every comment in the files under path (default .) with\n"+
+			"its score and detection reasons, without changing any files.")
+	opts.scanFlags(fs, "json")
+	opts.changeFlags(fs)
+	fs.IntVar(&opts.commitCount, "commits", 0, "Also check the last `n` commit messages")
+	output := fs.String("o", "", "Write the report to `file` instead of stdout")
+	path := pathArg(fs, parseArgs(fs, args), ".")
+
+	startReport()
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		defer f.Close()
+		reportOut = f
+	}
+
+	failed := scanTree(path)
+	if opts.commitCount > 0 {
+		addCommits(path)
+	}
+	if opts.format == "text" {
+		printFindings()
+	} else {
+		writeReport()
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d files could not be read\n", failed)
+	}
+}
+
+// scanTree scores every file under path into runReport, creating it if
+// --format is text, and returns the number of files that failed
+func scanTree(path string) int {
+	if runReport == nil {
+		runReport = report.New(0)
+	}
+	files, _ := findFiles(path)
+
+	failed := 0
+	for _, file := range files {
+		sf, err := scanFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", file.Path, err)
+			failed++
+			continue
+		}
+		if sf.ignored() {
+			rf := sf.skipped()
+			checkBaseline(&rf, sf)
+			runReport.Add(rf)
+			continue
+		}
+		rf := report.NewFile(diffPath(file.Path), file.Lang.Name, sf.file.Content, sf.parser, sf.score, nil, nil)
+		checkBaseline(&rf, sf)
+		runReport.Add(rf)
+	}
+	return failed
+}
+
+// printFindings lists the AI-like comments in runReport, one per line in
+// the path:line:column form editors jump to
+func printFindings() {
+	for _, f := range runReport.Files {
+		for _, c := range f.Comments {
+			if !c.AILike || c.Baselined {
+				continue
+			}
+			fmt.Fprintf(reportOut, "%s:%d:%d: %s [%s]\n", f.Path, c.StartLine, c.StartColumn,
+				strings.Join(c.Reasons, "; "), strings.Join(c.Rules, ","))
+			if opts.verbose {
+				fmt.Fprintf(reportOut, "    %s (score %.2f)\n", c.Text, c.Score)
+			}
+		}
+		if opts.verbose && f.Skipped != "" {
+			fmt.Fprintf(reportOut, "%s: skipped (%s)\n", f.Path, f.Skipped)
+		}
+	}
+	for _, c := range runReport.Commits {
+		fmt.Fprintf(reportOut, "commit %s: %s [%s]\n", c.Hash, strings.Join(c.Reasons, "; "), strings.Join(c.Rules, ","))
+	}
+
+	s := runReport.Summary
+	if found := s.AIComments - s.Baselined; found == 0 {
+		fmt.Fprintf(logOut, "No new AI-like comments in %d files", s.Files)
+	} else {
+		fmt.Fprintf(logOut, "\nFound %d AI-like comments out of %d in %d files", found, s.Comments, s.Files)
+	}
+	if s.Baselined > 0 {
+		fmt.Fprintf(logOut, " (%d more in the baseline)", s.Baselined)
+	}
+	fmt.Fprintln(logOut)
+}
+
+// findFiles loads the config for path and returns the files under it
+// that are not ignored, with the settings of path itself
+func findFiles(path string) ([]walker.FileInfo, *config.Settings) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Error: path does not exist: %s\n", path)
+		os.Exit(2)
+	}
+
+	configs = config.NewLoader()
+	rootSettings, err := configs.For(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading config: %v\n", err)
+		os.Exit(2)
+	}
+	if opts.verbose {
+		for _, source := range rootSettings.Sources {
+			fmt.Fprintf(logOut, "Config: %s\n", source)
+		}
+	}
+
+	files, err := walker.Walk(path, func(dir string) ([]string, error) {
+		s, err := configs.For(dir)
+		if err != nil {
+			return nil, err
+		}
+		return s.Ignore, nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error walking path: %v\n", err)
+		os.Exit(2)
+	}
+
+	loadChanges(path)
+	if changes != nil {
+		changed := files[:0]
+		for _, file := range files {
+			if _, ok := changes.For(file.Path); ok {
+				changed = append(changed, file)
+			}
+		}
+		files = changed
+		if opts.verbose {
+			fmt.Fprintf(logOut, "%d files changed\n", len(files))
+		}
+	}
+	return files, rootSettings
+}
+
+// loadChanges reads the lines changed in git for --since or --staged
+func loadChanges(path string) {
+	var err error
+	switch {
+	case opts.since != "" && opts.staged:
+		err = fmt.Errorf("--since and --staged cannot be combined")
+	case opts.since != "":
+		changes, err = git.ChangedSince(path, opts.since)
+	case opts.staged:
+		changes, err = git.StagedChanges(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+}
+
+// scannedFile is a file read, parsed and scored with its settings
+type scannedFile struct {
+	info     walker.FileInfo
+	file     *fileio.File
+	settings *config.Settings
+	parser   parser.Parser
+	all      []parser.Comment // Every comment in the file
+	comments []parser.Comment // The comments scored, those on changed lines with --since or --staged
+	lines    git.LineSet      // Changed lines, with --since or --staged
+	unstaged bool             // With --staged, file holds the staged contents, which differ from the disk
+	score    detector.FileScore
+}
+
+// scanFile reads, parses and scores a file. Files marked
+// deaiify:ignore-file are not scored. With --staged the staged contents
+// are read.
+func scanFile(info walker.FileInfo) (*scannedFile, error) {
+	f, err := fileio.Read(info.Path)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := configs.For(info.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	sf := &scannedFile{info: info, settings: settings, parser: info.Lang.NewParser()}
+	if opts.staged {
+		blob, err := git.StagedBlob(info.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(blob, f.Raw) {
+			if f, err = fileio.Decode(info.Path, blob, f.Mode); err != nil {
+				return nil, err
+			}
+			sf.unstaged = true
+		}
+	}
+	sf.file = f
+
+	sf.all = sf.parser.Parse(f.Content).Comments
+	sf.comments = sf.all
+	if changes != nil {
+		sf.lines, _ = changes.For(info.Path)
+		sf.comments = onChangedLines(sf.all, sf.lines, nil)
+	}
+	if !sf.ignored() {
+		sf.score = detector.ScoreFile(info.Path, sf.comments, settings.Detector())
+	}
+	return sf, nil
+}
+
+// ignored reports whether the file is marked deaiify:ignore-file
+func (sf *scannedFile) ignored() bool {
+	return parser.IgnoresFile(sf.all)
+}
+
+// onChangedLines returns the comments with at least one line in lines.
+// lineMap, if not nil, maps each line of the content the comments are in
+// to the line it was in the content lines refers to, or 0 for a line
+// written since, which counts as changed.
+func onChangedLines(comments []parser.Comment, lines git.LineSet, lineMap []int) []parser.Comment {
+	var out []parser.Comment
+	for _, c := range comments {
+		last := c.LineNumber + strings.Count(c.Original, "\n")
+		for line := c.LineNumber; line <= last; line++ {
+			orig := line
+			if lineMap != nil && line-1 < len(lineMap) {
+				orig = lineMap[line-1]
+			}
+			if orig == 0 || lines.Contains(orig) {
+				out = append(out, c)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// skipped returns the report entry of an ignored file
+func (sf *scannedFile) skipped() report.File {
+	return report.File{
+		Path:     diffPath(sf.info.Path),
+		Language: sf.info.Lang.Name,
+		Skipped:  parser.IgnoreFileMarker,
+		Comments: []report.Comment{},
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"deaiify/internal/git"
+	"deaiify/internal/journal"
+)
+
+// journalRoot returns the directory whose journal records runs on path:
+// the top of its git repository, or else path itself, or the directory
+// holding it if it is a file
+func journalRoot(path string) string {
+	if top, err := git.TopLevel(path); err == nil {
+		return top
+	}
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		path = filepath.Dir(path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// runUndo restores the files changed by a run recorded in the journal
+func runUndo(args []string) {
+	fs := newFlagSet("undo", "[flags] [run-id]",
+		"Restores the files changed by a run (default: the latest run not yet undone).\n"+
+			"Runs are recorded at the top of the git repository the files are in, or\n"+
+			"in the directory deaiify fix was given outside git.")
+	force := fs.Bool("force", false, "Restore files even if they were edited after the run")
+	list := fs.Bool("list", false, "List recorded runs")
+	dir := fs.String("C", ".", "Undo runs on the files in `dir` instead of the current directory")
+	fs.BoolVar(&opts.verbose, "verbose", false, "List each restored file")
+	positional := parseArgs(fs, args)
+	if len(positional) > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	id := ""
+	if len(positional) == 1 {
+		id = positional[0]
+	}
+	root := journalRoot(*dir)
+
+	if *list {
+		runs, err := journal.Runs(root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading runs: %v\n", err)
+			os.Exit(2)
+		}
+		for _, m := range runs {
+			status := ""
+			if m.Undone {
+				status = " (undone)"
+			}
+			fmt.Printf("%s  %d files%s\n", m.ID, len(m.Files), status)
+		}
+		return
+	}
+
+	m, results, err := journal.Undo(root, id, *force)
+	if errors.Is(err, journal.ErrNoRuns) {
+		fmt.Println("Nothing to undo.")
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "  skipped %s: %v\n", r.Path, r.Err)
+			failed++
+		} else if opts.verbose {
+			fmt.Printf("  restored %s\n", r.Path)
+		}
+	}
+	fmt.Printf("Restored %d of %d files from run %s\n", len(results)-failed, len(results), m.ID)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,152 @@
+package baseline
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"deaiify/internal/fileio"
+)
+
+// Version identifies the baseline file layout
+const Version = 1
+
+// DefaultFile is the baseline scan reads when it exists
+const DefaultFile = ".deaiify-baseline.json"
+
+// Baseline lists findings that existed when it was written. They are not
+// reported again, so a scan gate can be added to a repository that
+// already has some.
+type Baseline struct {
+	Version  int     `json:"version"`
+	Findings []Entry `json:"findings"` // One per finding, so duplicates repeat
+}
+
+// Entry identifies one finding independently of its line number
+type Entry struct {
+	Fingerprint string `json:"fingerprint"`
+	Path        string `json:"path"`             // Slash-separated, relative to the directory of the baseline file
+	Symbol      string `json:"symbol,omitempty"` // Declaration the comment is in or documents
+	Text        string `json:"text"`             // Normalized comment text
+}
+
+// NewEntry builds the entry of a comment with the given text in symbol
+func NewEntry(path, symbol, text string) Entry {
+	e := Entry{Path: path, Symbol: symbol, Text: Normalize(text)}
+	sum := sha256.Sum256([]byte(e.Path + "\x00" + e.Symbol + "\x00" + e.Text))
+	e.Fingerprint = hex.EncodeToString(sum[:8])
+	return e
+}
+
+// Normalize lowercases text and collapses its whitespace, so reflowing or
+// reindenting a comment keeps its fingerprint
+func Normalize(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+// New creates an empty baseline
+func New() *Baseline {
+	return &Baseline{Version: Version, Findings: []Entry{}}
+}
+
+// Add records a finding
+func (b *Baseline) Add(e Entry) {
+	b.Findings = append(b.Findings, e)
+}
+
+// Read loads a baseline file
+func Read(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if b.Version != Version {
+		return nil, fmt.Errorf("%s: unsupported baseline version %d", path, b.Version)
+	}
+	if b.Findings == nil {
+		b.Findings = []Entry{}
+	}
+	return &b, nil
+}
+
+// Write saves the baseline with its findings sorted, so that rewriting it
+// gives small diffs
+func (b *Baseline) Write(path string) error {
+	sort.SliceStable(b.Findings, func(i, j int) bool {
+		x, y := b.Findings[i], b.Findings[j]
+		if x.Path != y.Path {
+			return x.Path < y.Path
+		}
+		if x.Symbol != y.Symbol {
+			return x.Symbol < y.Symbol
+		}
+		return x.Text < y.Text
+	})
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(b); err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	return fileio.WriteAtomic(path, buf.Bytes(), mode)
+}
+
+// Matcher checks findings against a baseline. Each entry covers one
+// finding, so a comment copied next to a baselined one is still new.
+type Matcher struct {
+	remaining map[string]int
+	matched   []Entry
+}
+
+// Matcher starts matching findings against b
+func (b *Baseline) Matcher() *Matcher {
+	m := &Matcher{remaining: make(map[string]int, len(b.Findings))}
+	for _, e := range b.Findings {
+		m.remaining[e.Fingerprint]++
+	}
+	return m
+}
+
+// Match reports whether e is in the baseline and not yet matched
+func (m *Matcher) Match(e Entry) bool {
+	if m.remaining[e.Fingerprint] == 0 {
+		return false
+	}
+	m.remaining[e.Fingerprint]--
+	m.matched = append(m.matched, e)
+	return true
+}
+
+// Prune drops the entries no longer found. An entry is only dropped if
+// scanned reports that its file was scanned, or the file is gone, so
+// pruning after scanning part of a tree keeps the rest. It returns the
+// number of entries dropped.
+func (b *Baseline) Prune(m *Matcher, scanned func(path string) bool) int {
+	kept := append([]Entry{}, m.matched...)
+	unmatched := make(map[string]int, len(m.remaining))
+	for fp, n := range m.remaining {
+		unmatched[fp] = n
+	}
+	for _, e := range b.Findings {
+		if unmatched[e.Fingerprint] > 0 && !scanned(e.Path) {
+			unmatched[e.Fingerprint]--
+			kept = append(kept, e)
+		}
+	}
+	dropped := len(b.Findings) - len(kept)
+	b.Findings = kept
+	return dropped
+}
@@ -0,0 +1,114 @@
+package baseline
+
+import (
+	"regexp"
+	"strings"
+
+	"deaiify/internal/parser"
+)
+
+var (
+	// Function declarations, e.g. "def load(", "fn parse(", "sub run {" or
+	// "func (s *Scanner) Scan("
+	funcDeclRe = regexp.MustCompile(`\b(?:def|fn|func|fun|function|sub)\s+(?:\([^)]*\)\s*)?([A-Za-z_$][\w$]*)`)
+	// Type declarations, e.g. "class Parser", "impl Scanner"
+	typeDeclRe = regexp.MustCompile(`\b(?:class|struct|interface|enum|trait|impl|module|object|namespace|record|type)\s+([A-Za-z_$][\w$]*)`)
+	// C-style declarations, where a name and "(" follow a return type or
+	// modifiers, e.g. "void run(" or "public static int parse("
+	cDeclRe = regexp.MustCompile(`^\s*(?:[\w$<>\[\]*&:,?]+\s+)+[*&]*([A-Za-z_$][\w$]*)\s*\(`)
+	// The first word of a line, after any closing braces
+	firstWordRe = regexp.MustCompile(`^[\s}]*([A-Za-z_]\w*)`)
+)
+
+// statementWords start lines that are statements inside a declaration,
+// never declarations themselves
+var statementWords = map[string]bool{
+	"if": true, "elif": true, "else": true, "elsif": true, "unless": true,
+	"for": true, "foreach": true, "while": true, "until": true, "do": true,
+	"loop": true, "switch": true, "match": true, "select": true, "case": true,
+	"default": true, "when": true, "try": true, "except": true, "catch": true,
+	"finally": true, "with": true, "return": true, "yield": true, "throw": true,
+	"raise": true, "defer": true, "go": true, "await": true, "new": true,
+	"delete": true, "assert": true, "print": true, "echo": true, "local": true,
+	"let": true, "var": true, "const": true, "lambda": true,
+}
+
+// Symbols returns the declaration each comment belongs to: the one the
+// parser says it documents, then the one containing it if p can tell
+// from the syntax tree, or else the name declared on the nearest line
+// above it with less indentation. Comments are blanked out first so that
+// commented-out code is never taken for a declaration. A comment outside
+// any declaration gets "".
+func Symbols(p parser.Parser, content string, comments []parser.Comment) []string {
+	var enclosingDecls []string
+	if e, ok := p.(parser.Encloser); ok {
+		enclosingDecls = e.Enclosing(content, comments)
+	}
+
+	code := []byte(content)
+	for _, c := range comments {
+		for i := c.Start; i < c.End && i < len(code); i++ {
+			if code[i] != '\n' {
+				code[i] = ' '
+			}
+		}
+	}
+	lines := strings.Split(string(code), "\n")
+	original := strings.Split(content, "\n")
+
+	symbols := make([]string, len(comments))
+	for i, c := range comments {
+		if c.Target != "" {
+			symbols[i] = c.Target
+			continue
+		}
+		if enclosingDecls != nil {
+			symbols[i] = enclosingDecls[i]
+			continue
+		}
+		line := c.LineNumber - 1
+		if line < 0 || line >= len(original) {
+			continue
+		}
+		symbols[i] = enclosing(lines, line, indentation(original[line]))
+	}
+	return symbols
+}
+
+// enclosing walks up from line through ever less indented lines and
+// returns the first declared name found, passing over statements such as
+// if and for
+func enclosing(lines []string, line, indent int) string {
+	for j := line - 1; j >= 0 && indent > 0; j-- {
+		if strings.TrimSpace(lines[j]) == "" {
+			continue
+		}
+		ind := indentation(lines[j])
+		if ind >= indent {
+			continue
+		}
+		if name := declaredName(lines[j]); name != "" {
+			return name
+		}
+		indent = ind
+	}
+	return ""
+}
+
+// declaredName returns the name a line declares, or ""
+func declaredName(line string) string {
+	if m := firstWordRe.FindStringSubmatch(line); m != nil && statementWords[m[1]] {
+		return ""
+	}
+	for _, re := range []*regexp.Regexp{funcDeclRe, typeDeclRe, cDeclRe} {
+		if m := re.FindStringSubmatch(line); m != nil && !statementWords[m[1]] {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// indentation returns the width of a line's leading whitespace
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"deaiify/internal/detector"
+	"deaiify/internal/lang"
+	"deaiify/internal/transformer"
+	"deaiify/internal/walker"
+)
+
+// FileName is the name of a config file
+const FileName = ".deaiify.json"
+
+// File is the contents of one config file. Unset fields inherit from
+// config files in parent directories and then the built-in defaults.
+type File struct {
+	Root            bool                `json:"root,omitempty"` // Stop looking in parent directories
+	Threshold       *float64            `json:"threshold,omitempty"`
+	AIPatterns      []string            `json:"ai_patterns,omitempty"`
+	HumanComments   []string            `json:"human_comments,omitempty"`
+	Typos           map[string]string   `json:"typos,omitempty"` // Merged by word; "" removes a word
+	TypoProbability *float64            `json:"typo_probability,omitempty"`
+	Ignore          []string            `json:"ignore,omitempty"`
+	Linters         map[string][]string `json:"linters,omitempty"` // Merged by language
+}
+
+// Settings is the effective configuration for a directory
+type Settings struct {
+	Threshold       float64             `json:"threshold"`
+	AIPatterns      []string            `json:"ai_patterns"`
+	HumanComments   []string            `json:"human_comments"`
+	Typos           map[string]string   `json:"typos"`
+	TypoProbability float64             `json:"typo_probability"`
+	Ignore          []string            `json:"ignore"`
+	Linters         map[string][]string `json:"linters"`
+
+	Sources []string `json:"-"` // Config files applied, outermost first
+}
+
+// Defaults returns the built-in settings
+func Defaults() *Settings {
+	s := &Settings{
+		Threshold:       detector.Threshold,
+		AIPatterns:      append([]string(nil), detector.AIPatternPrefixes...),
+		HumanComments:   append([]string(nil), transformer.HumanComments...),
+		Typos:           make(map[string]string, len(transformer.TypoMap)),
+		TypoProbability: transformer.TypoProbability,
+		Ignore:          append([]string(nil), walker.IgnorePatterns...),
+		Linters:         make(map[string][]string),
+	}
+	for word, typo := range transformer.TypoMap {
+		s.Typos[word] = typo
+	}
+	for _, l := range lang.All() {
+		for _, t := range l.Linters {
+			s.Linters[l.Name] = append(s.Linters[l.Name], t.Name)
+		}
+	}
+	return s
+}
+
+// Starter returns the contents of a new config file: the built-in values
+// of the settings projects most often tune, marked as the root config
+func Starter() []byte {
+	d := Defaults()
+	f := File{
+		Root:            true,
+		Threshold:       &d.Threshold,
+		AIPatterns:      d.AIPatterns,
+		HumanComments:   d.HumanComments,
+		TypoProbability: &d.TypoProbability,
+		Ignore:          d.Ignore,
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return append(data, '\n')
+}
+
+// Detector returns the detection settings
+func (s *Settings) Detector() detector.Settings {
+	return detector.Settings{Prefixes: s.AIPatterns, Threshold: s.Threshold}
+}
+
+// Configure sets a transformer's vocabulary
+func (s *Settings) Configure(t *transformer.Transformer) {
+	t.HumanComments = s.HumanComments
+	t.Typos = s.Typos
+	t.TypoProbability = s.TypoProbability
+}
+
+// apply returns a copy of s with f's fields overriding it
+func (s *Settings) apply(path string, f *File) *Settings {
+	out := *s
+	out.Sources = append(append([]string(nil), s.Sources...), path)
+	if f.Threshold != nil {
+		out.Threshold = *f.Threshold
+	}
+	if f.AIPatterns != nil {
+		out.AIPatterns = make([]string, len(f.AIPatterns))
+		for i, p := range f.AIPatterns {
+			out.AIPatterns[i] = strings.ToLower(p)
+		}
+	}
+	if f.HumanComments != nil {
+		out.HumanComments = f.HumanComments
+	}
+	if f.Typos != nil {
+		out.Typos = make(map[string]string, len(s.Typos))
+		for word, typo := range s.Typos {
+			out.Typos[word] = typo
+		}
+		for word, typo := range f.Typos {
+			word = strings.ToLower(word)
+			if typo == "" {
+				delete(out.Typos, word)
+			} else {
+				out.Typos[word] = typo
+			}
+		}
+	}
+	if f.TypoProbability != nil {
+		out.TypoProbability = *f.TypoProbability
+	}
+	if f.Ignore != nil {
+		out.Ignore = f.Ignore
+	}
+	if f.Linters != nil {
+		out.Linters = make(map[string][]string, len(s.Linters))
+		for name, order := range s.Linters {
+			out.Linters[name] = order
+		}
+		for name, order := range f.Linters {
+			out.Linters[name] = order
+		}
+	}
+	return &out
+}
+
+// Loader finds the config files that apply to each directory, reading
+// each directory at most once
+type Loader struct {
+	cache map[string]*Settings
+}
+
+// NewLoader creates an empty Loader
+func NewLoader() *Loader {
+	return &Loader{cache: make(map[string]*Settings)}
+}
+
+// For returns the effective settings for a file or directory. Config files
+// from the filesystem root down to path are applied in turn, like
+// .editorconfig, starting at the nearest one with "root": true.
+func (l *Loader) For(path string) (*Settings, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(abs); err == nil && !info.IsDir() {
+		abs = filepath.Dir(abs)
+	}
+	return l.dir(abs)
+}
+
+func (l *Loader) dir(dir string) (*Settings, error) {
+	if s, ok := l.cache[dir]; ok {
+		return s, nil
+	}
+
+	path := filepath.Join(dir, FileName)
+	f, err := Read(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var s *Settings
+	parent := filepath.Dir(dir)
+	switch {
+	case f != nil && f.Root, parent == dir:
+		s = Defaults()
+	default:
+		if s, err = l.dir(parent); err != nil {
+			return nil, err
+		}
+	}
+	if f != nil {
+		s = s.apply(path, f)
+	}
+
+	l.cache[dir] = s
+	return s, nil
+}
+
+// Read parses and validates one config file
+func Read(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f File
+	if len(bytes.TrimSpace(data)) == 0 {
+		return &f, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := f.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &f, nil
+}
+
+func (f *File) validate() error {
+	if f.Threshold != nil && (*f.Threshold < 0 || *f.Threshold > 1) {
+		return fmt.Errorf("threshold must be between 0 and 1")
+	}
+	if f.TypoProbability != nil && (*f.TypoProbability < 0 || *f.TypoProbability > 1) {
+		return fmt.Errorf("typo_probability must be between 0 and 1")
+	}
+	for _, pattern := range f.Ignore {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("ignore pattern %q: %w", pattern, err)
+		}
+	}
+
+	names := make([]string, 0, len(f.Linters))
+	for name := range f.Linters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		l := lang.ByName(name)
+		if l == nil {
+			return fmt.Errorf("linters: unknown language %q", name)
+		}
+		for _, tool := range f.Linters[name] {
+			if !knownTool(l, tool) {
+				return fmt.Errorf("linters: %s has no linter %q", name, tool)
+			}
+		}
+	}
+	return nil
+}
+
+func knownTool(l *lang.Language, name string) bool {
+	for _, t := range l.Linters {
+		if t.Is(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -40,54 +40,88 @@ var AIPatternPrefixes = []string{
 	"basically",
 }
 
+// Threshold is the default score at which a comment counts as AI-like
+const Threshold = 0.3
+
+// Settings tunes detection for a file
+type Settings struct {
+	Prefixes  []string // Lowercase openings that mark a comment as AI-like
+	Threshold float64
+}
+
+// DefaultSettings returns the built-in prefixes and threshold
+func DefaultSettings() Settings {
+	return Settings{Prefixes: AIPatternPrefixes, Threshold: Threshold}
+}
+
 // Emoji detection regex
 var emojiRe = regexp.MustCompile(`[\x{1F600}-\x{1F64F}]|[\x{1F300}-\x{1F5FF}]|[\x{1F680}-\x{1F6FF}]|[\x{1F1E0}-\x{1F1FF}]|[\x{2600}-\x{26FF}]|[\x{2700}-\x{27BF}]`)
 
+// Rule IDs of the checks, as reported alongside each reason
+const (
+	RuleAIPrefix       = "ai-prefix"
+	RuleEmoji          = "emoji"
+	RuleVerbose        = "verbose-comment"
+	RuleFormal         = "formal-language"
+	RuleCapitalization = "excessive-capitalization"
+)
+
 // DetectionResult describes why a comment was flagged
 type DetectionResult struct {
-	IsAILike    bool
-	Reasons     []string
-	Score       float64 // 0.0 to 1.0, how "AI-like"
+	IsAILike bool
+	Reasons  []string
+	Rules    []string // Rule ID of each reason
+	Score    float64  // 0.0 to 1.0, how "AI-like"
+}
+
+// flag records a failed check
+func (r *DetectionResult) flag(rule, reason string, weight float64) {
+	r.Rules = append(r.Rules, rule)
+	r.Reasons = append(r.Reasons, reason)
+	r.Score += weight
 }
 
 // DetectAIComment checks if a comment looks AI-generated
-func DetectAIComment(comment parser.Comment) DetectionResult {
+func DetectAIComment(comment parser.Comment, s Settings) DetectionResult {
 	result := DetectionResult{
 		Reasons: []string{},
+		Rules:   []string{},
 	}
+
+	// License headers, directives and shebangs aren't prose, and
+	// suppressed comments are left alone on purpose
+	if comment.Kind.IsProtected() || comment.Suppressed != "" {
+		return result
+	}
+
 	text := strings.ToLower(strings.TrimSpace(comment.Text))
 
 	// Check for AI prefix patterns
-	for _, prefix := range AIPatternPrefixes {
+	for _, prefix := range s.Prefixes {
 		if strings.HasPrefix(text, prefix) {
-			result.Reasons = append(result.Reasons, "starts with AI pattern: "+prefix)
-			result.Score += 0.4
+			result.flag(RuleAIPrefix, "starts with AI pattern: "+prefix, 0.4)
 			break
 		}
 	}
 
 	// Check for emoji
 	if emojiRe.MatchString(comment.Text) {
-		result.Reasons = append(result.Reasons, "contains emoji")
-		result.Score += 0.3
+		result.flag(RuleEmoji, "contains emoji", 0.3)
 	}
 
 	// Check for over-explanation (>100 chars for simple statements)
 	if len(comment.Text) > 100 && !comment.IsBlock {
-		result.Reasons = append(result.Reasons, "overly verbose single-line comment")
-		result.Score += 0.2
+		result.flag(RuleVerbose, "overly verbose single-line comment", 0.2)
 	}
 
 	// Check for overly formal language
 	if containsFormalLanguage(text) {
-		result.Reasons = append(result.Reasons, "uses overly formal language")
-		result.Score += 0.15
+		result.flag(RuleFormal, "uses overly formal language", 0.15)
 	}
 
 	// Check for excessive capitalization in explanations
 	if hasExcessiveCapitalization(comment.Text) {
-		result.Reasons = append(result.Reasons, "excessive capitalization")
-		result.Score += 0.1
+		result.flag(RuleCapitalization, "excessive capitalization", 0.1)
 	}
 
 	// Normalize score
@@ -95,7 +129,7 @@ func DetectAIComment(comment parser.Comment) DetectionResult {
 		result.Score = 1.0
 	}
 
-	result.IsAILike = result.Score >= 0.3
+	result.IsAILike = result.Score >= s.Threshold
 
 	return result
 }
@@ -20,7 +20,7 @@ type CommentScore struct {
 }
 
 // ScoreFile analyzes all comments in a file and returns an AI-likeness score
-func ScoreFile(path string, comments []parser.Comment) FileScore {
+func ScoreFile(path string, comments []parser.Comment, s Settings) FileScore {
 	score := FileScore{
 		Path:          path,
 		TotalComments: len(comments),
@@ -33,7 +33,7 @@ func ScoreFile(path string, comments []parser.Comment) FileScore {
 
 	var totalScore float64
 	for _, comment := range comments {
-		result := DetectAIComment(comment)
+		result := DetectAIComment(comment, s)
 		score.Details = append(score.Details, CommentScore{
 			Comment: comment,
 			Result:  result,
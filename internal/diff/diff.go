@@ -0,0 +1,222 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Context is the number of unchanged lines shown around each change
+const Context = 3
+
+// opKind is one step of an edit script
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// op applies to line a of the old text or line b of the new text
+type op struct {
+	kind opKind
+	a, b int
+}
+
+// Unified returns a git-style unified diff turning before into after, or
+// "" if they are equal. path is the file's slash-separated path relative
+// to the directory the patch is applied from.
+func Unified(path, before, after string) string {
+	if before == after {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "diff --git a/%s b/%s\n", path, path)
+	fmt.Fprintf(&sb, "--- a/%s\n", path)
+	fmt.Fprintf(&sb, "+++ b/%s\n", path)
+	sb.WriteString(Hunks(before, after))
+	return sb.String()
+}
+
+// Hunks returns just the @@ hunks of the diff from before to after,
+// without file headers
+func Hunks(before, after string) string {
+	a, b := splitLines(before), splitLines(after)
+	ops := editScript(a, b)
+
+	var sb strings.Builder
+	for _, h := range hunks(ops) {
+		writeHunk(&sb, ops[h[0]:h[1]], a, b)
+	}
+	return sb.String()
+}
+
+// LineMap returns, for each line of after, the 1-based number of the same
+// line in before, or 0 for a line that is not in before
+func LineMap(before, after string) []int {
+	a, b := splitLines(before), splitLines(after)
+	lines := make([]int, len(b))
+	for _, o := range editScript(a, b) {
+		if o.kind == opEqual {
+			lines[o.b] = o.a + 1
+		}
+	}
+	return lines
+}
+
+// splitLines splits text into lines that keep their newline, so a missing
+// newline at the end of the file shows up as a difference
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// editScript returns the shortest edit script from a to b using Myers'
+// O(ND) algorithm
+func editScript(a, b []string) []op {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		// Round d only reads diagonals -d-1..d+1 of the previous frontier
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the frontiers saved before each round from the end to
+// recover the script
+func backtrack(trace [][]int, a, b []string) []op {
+	var ops []op
+	x, y := len(a), len(b)
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v, shift := trace[d], d+1
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[shift+k-1] < v[shift+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[shift+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{opEqual, x, y})
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, op{opInsert, x, y - 1})
+			} else {
+				ops = append(ops, op{opDelete, x - 1, y})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// hunks groups changes that are within 2*Context lines of each other and
+// returns each group's [start, end) range in ops, context included
+func hunks(ops []op) [][2]int {
+	var groups [][2]int
+	for i, o := range ops {
+		if o.kind == opEqual {
+			continue
+		}
+		start, end := i-Context, i+1+Context
+		if start < 0 {
+			start = 0
+		}
+		if end > len(ops) {
+			end = len(ops)
+		}
+		if n := len(groups); n > 0 && groups[n-1][1] >= start {
+			groups[n-1][1] = end
+		} else {
+			groups = append(groups, [2]int{start, end})
+		}
+	}
+	return groups
+}
+
+// writeHunk writes one @@ hunk
+func writeHunk(sb *strings.Builder, ops []op, a, b []string) {
+	oldStart, newStart := ops[0].a+1, ops[0].b+1
+	oldCount, newCount := 0, 0
+	for _, o := range ops {
+		if o.kind != opInsert {
+			oldCount++
+		}
+		if o.kind != opDelete {
+			newCount++
+		}
+	}
+	// An empty range is numbered after the line it follows
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+
+	for _, o := range ops {
+		switch o.kind {
+		case opEqual:
+			writeLine(sb, ' ', a[o.a])
+		case opDelete:
+			writeLine(sb, '-', a[o.a])
+		case opInsert:
+			writeLine(sb, '+', b[o.b])
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// writeLine writes a diff line, marking a last line without a newline
+func writeLine(sb *strings.Builder, prefix byte, line string) {
+	sb.WriteByte(prefix)
+	sb.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		sb.WriteString("\n\\ No newline at end of file\n")
+	}
+}
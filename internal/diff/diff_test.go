@@ -0,0 +1,104 @@
+package diff
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var applyTests = []struct {
+	name   string
+	before string
+	after  string
+}{
+	{"one line changed", "a\nb\nc\n", "a\nB\nc\n"},
+	{"line added at start", "a\nb\n", "x\na\nb\n"},
+	{"line added at end", "a\nb\n", "a\nb\nx\n"},
+	{"line removed", "a\nb\nc\n", "a\nc\n"},
+	{"all lines removed", "a\nb\n", ""},
+	{"file created", "", "a\nb\n"},
+	{"newline added at end", "a\nb", "a\nb\n"},
+	{"newline removed at end", "a\nb\n", "a\nb"},
+	{"last line changed without newline", "a\nb", "a\nc"},
+	{"two hunks", numbered(1, 20), strings.Replace(strings.Replace(numbered(1, 20), "2\n", "two\n", 1), "19\n", "nineteen\n", 1)},
+	{"nearby changes in one hunk", numbered(1, 20), strings.Replace(strings.Replace(numbered(1, 20), "5\n", "five\n", 1), "10\n", "ten\n", 1)},
+	{"repeated lines", "x\nx\nx\ny\nx\n", "x\ny\nx\nx\nx\n"},
+	{"CRLF line endings", "a\r\nb\r\nc\r\n", "a\r\nB\r\nc\r\n"},
+}
+
+// numbered returns the lines from to to, one number per line
+func numbered(from, to int) string {
+	var sb strings.Builder
+	for i := from; i <= to; i++ {
+		sb.WriteString(strconv.Itoa(i))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// TestUnifiedApplies checks that git apply accepts each diff and that
+// applying it to before gives after
+func TestUnifiedApplies(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	for _, tt := range applyTests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "src", "file.txt")
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(tt.before), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			patch := Unified("src/file.txt", tt.before, tt.after)
+			cmd := exec.Command("git", "apply", "-")
+			cmd.Dir = dir
+			cmd.Stdin = strings.NewReader(patch)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git apply: %v\n%s\npatch:\n%s", err, out, patch)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.after {
+				t.Errorf("applied patch gives %q, want %q\npatch:\n%s", got, tt.after, patch)
+			}
+		})
+	}
+}
+
+func TestUnifiedEqual(t *testing.T) {
+	if d := Unified("a.txt", "a\n", "a\n"); d != "" {
+		t.Errorf("Unified() = %q, want empty", d)
+	}
+}
+
+func TestLineMap(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []int
+	}{
+		{"unchanged", "a\nb\n", "a\nb\n", []int{1, 2}},
+		{"line inserted", "a\nb\n", "a\nx\nb\n", []int{1, 0, 2}},
+		{"line removed", "a\nb\nc\n", "a\nc\n", []int{1, 3}},
+		{"line replaced", "a\nb\nc\n", "a\nB\nc\n", []int{1, 0, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LineMap(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LineMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package fileio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is a text encoding recognized from a byte order mark
+type Encoding int
+
+const (
+	UTF8 Encoding = iota
+	UTF16LE
+	UTF16BE
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// File is a source file decoded for processing. Content has no byte order
+// mark and uses "\n" line endings when the file consistently used "\r\n",
+// so parser offsets and line splitting work the same for every file.
+// Encode restores the original form.
+type File struct {
+	Path     string
+	Mode     os.FileMode
+	Raw      []byte // Bytes as read from disk
+	Content  string
+	Encoding Encoding
+	BOM      bool
+	CRLF     bool // Every line ending was "\r\n" and Content was converted
+}
+
+// Read loads and decodes a file
+func Read(path string) (*File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// Keep setuid, setgid and sticky along with the permission bits
+	return Decode(path, raw, info.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky))
+}
+
+// Decode decodes the contents of the file at path from raw, for contents
+// that come from somewhere other than the file itself, such as the git
+// index
+func Decode(path string, raw []byte, mode os.FileMode) (*File, error) {
+	f := &File{Path: path, Mode: mode, Raw: raw}
+	data := raw
+	var err error
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		f.BOM = true
+		data = data[len(bomUTF8):]
+	case bytes.HasPrefix(data, bomUTF16LE):
+		f.BOM, f.Encoding = true, UTF16LE
+		data = data[len(bomUTF16LE):]
+	case bytes.HasPrefix(data, bomUTF16BE):
+		f.BOM, f.Encoding = true, UTF16BE
+		data = data[len(bomUTF16BE):]
+	}
+
+	content := string(data)
+	if f.Encoding != UTF8 {
+		if content, err = decodeUTF16(data, f.byteOrder()); err != nil {
+			return nil, err
+		}
+	}
+
+	if isCRLF(content) {
+		f.CRLF = true
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+	}
+	f.Content = content
+	return f, nil
+}
+
+// Encode converts content back to the file's encoding, BOM and line endings
+func (f *File) Encode(content string) []byte {
+	if f.CRLF {
+		content = strings.ReplaceAll(content, "\n", "\r\n")
+	}
+
+	var out []byte
+	switch f.Encoding {
+	case UTF16LE:
+		out = append(out, bomUTF16LE...)
+		out = append(out, encodeUTF16(content, binary.LittleEndian)...)
+	case UTF16BE:
+		out = append(out, bomUTF16BE...)
+		out = append(out, encodeUTF16(content, binary.BigEndian)...)
+	default:
+		if f.BOM {
+			out = append(out, bomUTF8...)
+		}
+		out = append(out, content...)
+	}
+	return out
+}
+
+// IsText reports whether the file's bytes are UTF-8 text, which a unified
+// diff can show directly
+func (f *File) IsText() bool {
+	return f.Encoding == UTF8 && utf8.Valid(f.Raw)
+}
+
+func (f *File) byteOrder() binary.ByteOrder {
+	if f.Encoding == UTF16BE {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// isCRLF reports whether content has line endings and all of them are
+// "\r\n". Mixed files are left alone so no line changes its ending.
+func isCRLF(content string) bool {
+	lf := strings.Count(content, "\n")
+	return lf > 0 && strings.Count(content, "\r\n") == lf
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) (string, error) {
+	if len(data)%2 != 0 {
+		return "", errors.New("invalid UTF-16: odd number of bytes")
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+func encodeUTF16(content string, order binary.ByteOrder) []byte {
+	units := utf16.Encode([]rune(content))
+	out := make([]byte, 2*len(units))
+	for i, u := range units {
+		order.PutUint16(out[2*i:], u)
+	}
+	return out
+}
+
+// WriteAtomic replaces path with data by writing a temporary file in the
+// same directory and renaming it over the original, so readers never see
+// a partial file. The new file gets mode and, where possible, the
+// original's owner. Symlinks are followed so the link itself survives.
+func WriteAtomic(path string, data []byte, mode os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	info, statErr := os.Stat(path)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".deaiify-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if statErr == nil {
+		if err := copyOwner(tmp.Name(), info); err != nil {
+			return err
+		}
+	}
+	// After the chown, which clears setuid and setgid
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
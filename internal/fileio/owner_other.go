@@ -0,0 +1,10 @@
+//go:build !unix
+
+package fileio
+
+import "os"
+
+// copyOwner is a no-op where files have no Unix owner
+func copyOwner(path string, info os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package fileio
+
+import (
+	"os"
+	"syscall"
+)
+
+// copyOwner gives path the owner and group of info. Only root may give a
+// file away, so a permission error is ignored and the file keeps the
+// current user as owner.
+func copyOwner(path string, info os.FileInfo) error {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if err := os.Lchown(path, int(st.Uid), int(st.Gid)); err != nil && !os.IsPermission(err) {
+		return err
+	}
+	return nil
+}
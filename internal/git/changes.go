@@ -0,0 +1,180 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LineSet holds 1-based line numbers. A nil LineSet holds every line.
+type LineSet map[int]bool
+
+// Contains reports whether line is in the set
+func (s LineSet) Contains(line int) bool {
+	return s == nil || s[line]
+}
+
+// Changes maps the absolute paths of changed files to their added or
+// modified lines
+type Changes map[string]LineSet
+
+// For returns the changed lines of the file at path, and false if the
+// file did not change
+func (c Changes) For(path string) (LineSet, bool) {
+	lines, ok := c[resolve(path)]
+	return lines, ok
+}
+
+// hunkRe matches the header of a hunk and captures its range in the new file
+var hunkRe = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// ChangedSince returns the lines added or modified in the repository at
+// path since it branched off ref: committed, staged and unstaged changes,
+// and every line of untracked files that are not ignored
+func ChangedSince(path, ref string) (Changes, error) {
+	top, err := TopLevel(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(ref, "-") || exec.Command("git", "-C", top, "rev-parse", "--verify", "--quiet", ref+"^{commit}").Run() != nil {
+		return nil, fmt.Errorf("unknown revision %q", ref)
+	}
+	base := ref
+	if out, err := exec.Command("git", "-C", top, "merge-base", ref, "HEAD").Output(); err == nil {
+		base = strings.TrimSpace(string(out))
+	}
+
+	changes, err := diffLines(top, base)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := exec.Command("git", "-C", top, "ls-files", "--others", "--exclude-standard", "-z").Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing untracked files: %w", err)
+	}
+	for _, name := range strings.Split(string(out), "\x00") {
+		if name != "" {
+			changes[filepath.Join(top, filepath.FromSlash(name))] = nil
+		}
+	}
+	return changes, nil
+}
+
+// StagedChanges returns the lines added or modified in the index of the
+// repository at path, relative to HEAD
+func StagedChanges(path string) (Changes, error) {
+	top, err := TopLevel(path)
+	if err != nil {
+		return nil, err
+	}
+	return diffLines(top, "--cached")
+}
+
+// StagedBlob returns the contents the file at path has in the index
+func StagedBlob(path string) ([]byte, error) {
+	top, err := TopLevel(path)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(top, resolve(path))
+	if err != nil {
+		return nil, err
+	}
+	out, err := exec.Command("git", "-C", top, "cat-file", "blob", ":"+filepath.ToSlash(rel)).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s is not staged", path)
+	}
+	return out, nil
+}
+
+// diffLines runs git diff in the top directory of a repository with args
+// and collects the new side of each hunk
+func diffLines(top string, args ...string) (Changes, error) {
+	cmdArgs := append([]string{"-C", top, "-c", "core.quotePath=false", "diff",
+		"--no-color", "--no-ext-diff", "--unified=0", "--src-prefix=a/", "--dst-prefix=b/"}, args...)
+	out, err := exec.Command("git", append(cmdArgs, "--")...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff: %w", err)
+	}
+
+	// A +++ line only names a file in the header of a diff --git block;
+	// inside a hunk it is an added line that starts with "++"
+	changes := make(Changes)
+	var current LineSet
+	inHeader := false
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			current = nil
+			inHeader = true
+		case inHeader && strings.HasPrefix(line, "+++ "):
+			name := diffName(strings.TrimPrefix(line, "+++ "))
+			if name == "/dev/null" {
+				continue
+			}
+			current = make(LineSet)
+			changes[filepath.Join(top, filepath.FromSlash(strings.TrimPrefix(name, "b/")))] = current
+		case strings.HasPrefix(line, "@@ "):
+			inHeader = false
+			m := hunkRe.FindStringSubmatch(line)
+			if current == nil || m == nil {
+				continue
+			}
+			start, _ := strconv.Atoi(m[1])
+			count := 1
+			if m[2] != "" {
+				count, _ = strconv.Atoi(m[2])
+			}
+			for i := start; i < start+count; i++ {
+				current[i] = true
+			}
+		}
+	}
+	return changes, scanner.Err()
+}
+
+// diffName returns a file name from a diff header, which git quotes
+// when it holds control characters, quotes or backslashes
+func diffName(name string) string {
+	if strings.HasPrefix(name, `"`) {
+		if unquoted, err := strconv.Unquote(name); err == nil {
+			return unquoted
+		}
+	}
+	return name
+}
+
+// TopLevel returns the root of the working tree containing path
+func TopLevel(path string) (string, error) {
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		path = filepath.Dir(path)
+	}
+	out, err := exec.Command("git", "-C", path, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s is not in a git repository", path)
+	}
+	return resolve(strings.TrimSpace(string(out))), nil
+}
+
+// resolve returns the absolute path of path with symlinks resolved, so
+// paths from git and from the walker compare equal
+func resolve(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if real, err := filepath.EvalSymlinks(abs); err == nil {
+		return real
+	}
+	return abs
+}
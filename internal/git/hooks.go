@@ -0,0 +1,38 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// HooksDir returns the hooks directory of the repository at path: the
+// core.hooksPath setting if there is one, and otherwise the hooks
+// directory of the repository, which linked worktrees share
+func HooksDir(path string) (string, error) {
+	out, err := exec.Command("git", "-C", path, "rev-parse", "--git-common-dir").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s is not a git repository", path)
+	}
+	dir := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(path, dir)
+	}
+
+	// A relative hooksPath is relative to where hooks run: the top of
+	// the working tree, or the repository itself when it is bare
+	out, err = exec.Command("git", "-C", path, "config", "--type=path", "--get", "core.hooksPath").Output()
+	if err != nil {
+		return filepath.Join(dir, "hooks"), nil
+	}
+	hooks := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(hooks) {
+		base := dir
+		if top, err := TopLevel(path); err == nil {
+			base = top
+		}
+		hooks = filepath.Join(base, hooks)
+	}
+	return hooks, nil
+}
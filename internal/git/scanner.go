@@ -7,11 +7,20 @@ import (
 	"strings"
 )
 
+// Rule IDs of the commit message checks
+const (
+	RuleCommitEmoji    = "commit-emoji"
+	RuleCommitGitmoji  = "commit-gitmoji"
+	RuleCommitFooter   = "commit-ai-footer"
+	RuleCommitPhrasing = "commit-ai-phrasing"
+)
+
 // CommitWarning describes an issue with a commit
 type CommitWarning struct {
 	Hash    string
 	Subject string
 	Reasons []string
+	Rules   []string // Rule ID of each reason
 }
 
 // AI footers to detect
@@ -104,39 +113,7 @@ func parseCommits(output string) []CommitWarning {
 			body = lines[2]
 		}
 
-		fullMessage := subject + "\n" + body
-		lowerMessage := strings.ToLower(fullMessage)
-
-		var reasons []string
-
-		// Check for emoji
-		if emojiRe.MatchString(fullMessage) {
-			reasons = append(reasons, "contains emoji")
-		}
-
-		// Check for text-based emoji
-		for _, emoji := range textEmojis {
-			if strings.Contains(lowerMessage, emoji) {
-				reasons = append(reasons, "contains gitmoji ("+emoji+")")
-				break
-			}
-		}
-
-		// Check for AI footers
-		for _, footer := range aiFooters {
-			if strings.Contains(lowerMessage, footer) {
-				reasons = append(reasons, "contains AI footer: "+footer)
-			}
-		}
-
-		// Check for AI-like patterns
-		for _, pattern := range aiCommitPatterns {
-			if strings.Contains(lowerMessage, pattern) {
-				reasons = append(reasons, "uses AI-like phrasing: \""+pattern+"\"")
-				break
-			}
-		}
-
+		reasons, rules := checkMessage(subject + "\n" + body)
 		if len(reasons) > 0 {
 			// Truncate hash for display
 			shortHash := hash
@@ -147,6 +124,7 @@ func parseCommits(output string) []CommitWarning {
 				Hash:    shortHash,
 				Subject: truncate(subject, 60),
 				Reasons: reasons,
+				Rules:   rules,
 			})
 		}
 	}
@@ -154,6 +132,80 @@ func parseCommits(output string) []CommitWarning {
 	return warnings
 }
 
+// checkMessage returns the reasons a commit message looks AI-written and
+// the rule ID of each
+func checkMessage(fullMessage string) (reasons, rules []string) {
+	lowerMessage := strings.ToLower(fullMessage)
+
+	flag := func(rule, reason string) {
+		rules = append(rules, rule)
+		reasons = append(reasons, reason)
+	}
+
+	// Check for emoji
+	if emojiRe.MatchString(fullMessage) {
+		flag(RuleCommitEmoji, "contains emoji")
+	}
+
+	// Check for text-based emoji
+	for _, emoji := range textEmojis {
+		if strings.Contains(lowerMessage, emoji) {
+			flag(RuleCommitGitmoji, "contains gitmoji ("+emoji+")")
+			break
+		}
+	}
+
+	// Check for AI footers
+	for _, footer := range aiFooters {
+		if strings.Contains(lowerMessage, footer) {
+			flag(RuleCommitFooter, "contains AI footer: "+footer)
+		}
+	}
+
+	// Check for AI-like patterns
+	for _, pattern := range aiCommitPatterns {
+		if strings.Contains(lowerMessage, pattern) {
+			flag(RuleCommitPhrasing, "uses AI-like phrasing: \""+pattern+"\"")
+			break
+		}
+	}
+
+	return reasons, rules
+}
+
+// CheckMessage checks a commit message that has not been committed yet,
+// such as the one a commit-msg hook receives. Comment lines and anything
+// below the scissors line of "git commit -v" are ignored. It returns nil
+// if the message looks fine.
+func CheckMessage(message string) *CommitWarning {
+	message = StripComments(message)
+	reasons, rules := checkMessage(message)
+	if len(reasons) == 0 {
+		return nil
+	}
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return &CommitWarning{Subject: truncate(subject, 60), Reasons: reasons, Rules: rules}
+}
+
+// scissors marks where "git commit -v" appends the diff
+const scissors = "# ------------------------ >8 ------------------------"
+
+// StripComments removes the lines git strips from a commit message
+// before committing: "#" comments and everything after the scissors line
+func StripComments(message string) string {
+	var kept []string
+	for _, line := range strings.Split(message, "\n") {
+		if line == scissors {
+			break
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
 func truncate(s string, max int) string {
 	if len(s) <= max {
 		return s
@@ -0,0 +1,240 @@
+package journal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"deaiify/internal/fileio"
+)
+
+// Dir is the directory, relative to the root of the files deaiify
+// changes, holding its state
+const Dir = ".deaiify"
+
+// ErrNoRuns is returned by undo when there is nothing to restore
+var ErrNoRuns = errors.New("no runs to undo")
+
+// Edit records one change made to a file
+type Edit struct {
+	Line        int    `json:"line"`
+	Action      string `json:"action"`
+	Original    string `json:"original,omitempty"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// File records a file modified by a run
+type File struct {
+	Path   string      `json:"path"`   // Absolute path
+	Backup string      `json:"backup"` // Original contents, relative to the run directory
+	Mode   os.FileMode `json:"mode"`
+	Before string      `json:"before"` // SHA-256 of the original contents
+	After  string      `json:"after"`  // SHA-256 of the contents deaiify wrote
+	Edits  []Edit      `json:"edits"`
+}
+
+// Manifest describes a run
+type Manifest struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	Seed    int64     `json:"seed"`
+	Undone  bool      `json:"undone,omitempty"`
+	Files   []File    `json:"files"`
+}
+
+// Journal records the original contents of every file a run modifies
+type Journal struct {
+	dir      string
+	manifest Manifest
+}
+
+// New starts a journal for a run under root/.deaiify/runs. Nothing is
+// written until the first file is recorded.
+func New(root string, seed int64) *Journal {
+	now := time.Now()
+	id := now.Format("20060102-150405")
+	runs := filepath.Join(root, Dir, "runs")
+	for n := 2; exists(filepath.Join(runs, id)); n++ {
+		id = fmt.Sprintf("%s-%d", now.Format("20060102-150405"), n)
+	}
+	return &Journal{
+		dir:      filepath.Join(runs, id),
+		manifest: Manifest{ID: id, Created: now, Seed: seed},
+	}
+}
+
+// ID returns the run's identifier
+func (j *Journal) ID() string {
+	return j.manifest.ID
+}
+
+// Empty reports whether no file has been recorded
+func (j *Journal) Empty() bool {
+	return len(j.manifest.Files) == 0
+}
+
+// Record saves a file's original contents and mode before it is
+// overwritten with updated
+func (j *Journal) Record(path string, original, updated []byte, mode os.FileMode, edits []Edit) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	backup := filepath.Join("files", fmt.Sprintf("%04d", len(j.manifest.Files)+1))
+	if err := os.MkdirAll(filepath.Join(j.dir, "files"), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(j.dir, backup), original, 0600); err != nil {
+		return err
+	}
+
+	j.manifest.Files = append(j.manifest.Files, File{
+		Path:   abs,
+		Backup: filepath.ToSlash(backup),
+		Mode:   mode,
+		Before: hash(original),
+		After:  hash(updated),
+		Edits:  edits,
+	})
+	return writeManifest(j.dir, &j.manifest)
+}
+
+// Runs returns the manifests of the runs under root, oldest first
+func Runs(root string) ([]Manifest, error) {
+	entries, err := os.ReadDir(filepath.Join(root, Dir, "runs"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []Manifest
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		m, err := readManifest(filepath.Join(root, Dir, "runs", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *m)
+	}
+	sort.Slice(runs, func(a, b int) bool {
+		return runs[a].Created.Before(runs[b].Created)
+	})
+	return runs, nil
+}
+
+// Restored describes what undo did with one file
+type Restored struct {
+	Path string
+	Err  error // Why the file was left alone, nil if it was restored
+}
+
+// Undo restores the files changed by run id, or by the latest run not yet
+// undone when id is empty. Files edited since the run are left alone
+// unless force is set.
+func Undo(root, id string, force bool) (*Manifest, []Restored, error) {
+	if id == "" {
+		runs, err := Runs(root)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := len(runs) - 1; i >= 0; i-- {
+			if !runs[i].Undone {
+				id = runs[i].ID
+				break
+			}
+		}
+		if id == "" {
+			return nil, nil, ErrNoRuns
+		}
+	}
+
+	dir := filepath.Join(root, Dir, "runs", id)
+	m, err := readManifest(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var results []Restored
+	complete := true
+	for _, f := range m.Files {
+		err := restore(dir, f, force)
+		if err != nil {
+			complete = false
+		}
+		results = append(results, Restored{Path: f.Path, Err: err})
+	}
+
+	if complete {
+		m.Undone = true
+		if err := writeManifest(dir, m); err != nil {
+			return m, results, err
+		}
+	}
+	return m, results, nil
+}
+
+// restore puts back one file's original contents and mode
+func restore(dir string, f File, force bool) error {
+	original, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Backup)))
+	if err != nil {
+		return err
+	}
+
+	current, err := os.ReadFile(f.Path)
+	switch {
+	case err == nil && bytes.Equal(current, original):
+		// Already restored
+		return os.Chmod(f.Path, f.Mode)
+	case err == nil && hash(current) != f.After && !force:
+		return errors.New("changed since the run (use --force to overwrite)")
+	case err != nil && !os.IsNotExist(err):
+		return err
+	}
+
+	return fileio.WriteAtomic(f.Path, original, f.Mode)
+}
+
+func readManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", dir, err)
+	}
+	return &m, nil
+}
+
+func writeManifest(dir string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, "manifest.json.tmp")
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, "manifest.json"))
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,107 @@
+package lang
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"deaiify/internal/linter"
+	"deaiify/internal/parser"
+	"deaiify/internal/transformer"
+	"deaiify/internal/verify"
+)
+
+// Language describes everything deaiify needs to process one language
+type Language struct {
+	Name        string                    // Key used by directives and linters, e.g. "js"
+	DisplayName string                    // Human-readable name, e.g. "TypeScript/JavaScript"
+	Extensions  []string                  // Lowercase file extensions including the dot
+	Filenames   []string                  // Exact file names matched regardless of extension
+	NewParser   func() parser.Parser      // Owns the comment syntax
+	Structure   transformer.StructureFunc // nil when layout edits are unsafe
+	Verify      verify.Options            // Code edits the transformations may make
+	SyntaxCheck *linter.Tool              // nil when there is no standalone checker
+	Linters     []linter.Tool             // In order of preference
+}
+
+var (
+	languages   []*Language
+	byName      = map[string]*Language{}
+	byExtension = map[string]*Language{}
+	byFilename  = map[string]*Language{}
+)
+
+// Register adds a language to the registry. Names, extensions and file
+// names must not already be registered.
+func Register(l *Language) error {
+	if _, ok := byName[l.Name]; ok {
+		return fmt.Errorf("language %q already registered", l.Name)
+	}
+	for _, ext := range l.Extensions {
+		if other, ok := byExtension[ext]; ok {
+			return fmt.Errorf("extension %s already registered by %s", ext, other.Name)
+		}
+	}
+	for _, name := range l.Filenames {
+		if other, ok := byFilename[name]; ok {
+			return fmt.Errorf("file name %s already registered by %s", name, other.Name)
+		}
+	}
+
+	languages = append(languages, l)
+	byName[l.Name] = l
+	for _, ext := range l.Extensions {
+		byExtension[ext] = l
+	}
+	for _, name := range l.Filenames {
+		byFilename[name] = l
+	}
+	return nil
+}
+
+// All returns the registered languages in registration order
+func All() []*Language {
+	return languages
+}
+
+// ByName returns the language with the given key, or nil
+func ByName(name string) *Language {
+	return byName[name]
+}
+
+// ForPath returns the language for a file path, matching the file name
+// before the extension. It returns nil for unsupported files.
+func ForPath(path string) *Language {
+	if l, ok := byFilename[filepath.Base(path)]; ok {
+		return l
+	}
+	return byExtension[strings.ToLower(filepath.Ext(path))]
+}
+
+// Linters returns every language's linters keyed by language name
+func Linters() map[string][]linter.Tool {
+	tools := make(map[string][]linter.Tool)
+	for _, l := range languages {
+		if len(l.Linters) > 0 {
+			tools[l.Name] = l.Linters
+		}
+	}
+	return tools
+}
+
+// SyntaxChecks returns every language's syntax checker keyed by language name
+func SyntaxChecks() map[string]linter.Tool {
+	checks := make(map[string]linter.Tool)
+	for _, l := range languages {
+		if l.SyntaxCheck != nil {
+			checks[l.Name] = *l.SyntaxCheck
+		}
+	}
+	return checks
+}
+
+func mustRegister(l *Language) {
+	if err := Register(l); err != nil {
+		panic(err)
+	}
+}
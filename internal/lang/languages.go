@@ -0,0 +1,168 @@
+package lang
+
+import (
+	"deaiify/internal/linter"
+	"deaiify/internal/parser"
+	"deaiify/internal/transformer"
+	"deaiify/internal/verify"
+)
+
+func init() {
+	mustRegister(&Language{
+		Name:        "js",
+		DisplayName: "JS",
+		Extensions:  []string{".ts", ".tsx", ".js", ".jsx"},
+		NewParser:   func() parser.Parser { return parser.NewJavaScriptParser() },
+		Structure:   (*transformer.Transformer).BraceStructure,
+		Verify:      verify.Options{TrailingCommas: ")]}"},
+		SyntaxCheck: &linter.JSSyntaxCheck,
+		Linters:     linter.JSTools,
+	})
+
+	mustRegister(&Language{
+		Name:        "py",
+		DisplayName: "Python",
+		Extensions:  []string{".py"},
+		NewParser:   func() parser.Parser { return parser.NewPythonParser() },
+		Structure:   (*transformer.Transformer).PythonStructure,
+		Verify:      verify.Options{TrailingCommas: "]}", Placeholder: "pass"},
+		SyntaxCheck: &linter.PySyntaxCheck,
+		Linters:     linter.PyTools,
+	})
+
+	// gofmt and rustfmt own the layout of Go and Rust, and Rust's (x,) is
+	// a tuple, so neither gets structural edits
+	mustRegister(&Language{
+		Name:        "go",
+		DisplayName: "Go",
+		Extensions:  []string{".go"},
+		NewParser:   func() parser.Parser { return parser.NewGoParser() },
+		SyntaxCheck: &linter.GoSyntaxCheck,
+		Linters:     linter.GoTools,
+	})
+
+	mustRegister(&Language{
+		Name:        "rust",
+		DisplayName: "Rust",
+		Extensions:  []string{".rs"},
+		NewParser:   func() parser.Parser { return parser.NewRustParser() },
+		SyntaxCheck: &linter.RustSyntaxCheck,
+		Linters:     linter.RustTools,
+	})
+
+	mustRegister(&Language{
+		Name:        "java",
+		DisplayName: "Java",
+		Extensions:  []string{".java"},
+		NewParser:   func() parser.Parser { return parser.NewJavaParser() },
+		Structure:   (*transformer.Transformer).BraceStructure,
+		Verify:      verify.Options{TrailingCommas: ")]}"},
+		SyntaxCheck: &linter.JavaSyntaxCheck,
+		Linters:     linter.JavaTools,
+	})
+
+	mustRegister(&Language{
+		Name:        "kotlin",
+		DisplayName: "Kotlin",
+		Extensions:  []string{".kt", ".kts"},
+		NewParser:   func() parser.Parser { return parser.NewKotlinParser() },
+		Structure:   (*transformer.Transformer).BraceStructure,
+		Verify:      verify.Options{TrailingCommas: ")]}"},
+		Linters:     linter.KotlinTools,
+	})
+
+	mustRegister(&Language{
+		Name:        "c",
+		DisplayName: "C",
+		Extensions:  []string{".c", ".h"},
+		NewParser:   func() parser.Parser { return parser.NewCParser() },
+		Structure:   (*transformer.Transformer).BraceStructure,
+		Verify:      verify.Options{TrailingCommas: ")]}"},
+		Linters:     linter.CTools,
+	})
+
+	mustRegister(&Language{
+		Name:        "cpp",
+		DisplayName: "C++",
+		Extensions:  []string{".cpp", ".hpp", ".cc"},
+		NewParser:   func() parser.Parser { return parser.NewCppParser() },
+		Structure:   (*transformer.Transformer).BraceStructure,
+		Verify:      verify.Options{TrailingCommas: ")]}"},
+		Linters:     linter.CTools,
+	})
+
+	mustRegister(&Language{
+		Name:        "csharp",
+		DisplayName: "C#",
+		Extensions:  []string{".cs"},
+		NewParser:   func() parser.Parser { return parser.NewCSharpParser() },
+		Structure:   (*transformer.Transformer).BraceStructure,
+		Verify:      verify.Options{TrailingCommas: ")]}"},
+		Linters:     linter.CSharpTools,
+	})
+
+	// Heredocs, block scalars and recipes make line-based structural edits
+	// unsafe in the hash-comment languages
+	mustRegister(&Language{
+		Name:        "shell",
+		DisplayName: "Shell",
+		Extensions:  []string{".sh", ".bash"},
+		NewParser:   func() parser.Parser { return parser.NewShellParser() },
+		SyntaxCheck: &linter.ShellSyntaxCheck,
+		Linters:     linter.ShellTools,
+	})
+
+	mustRegister(&Language{
+		Name:        "zsh",
+		DisplayName: "Zsh",
+		Extensions:  []string{".zsh"},
+		NewParser:   func() parser.Parser { return parser.NewZshParser() },
+		SyntaxCheck: &linter.ZshSyntaxCheck,
+	})
+
+	mustRegister(&Language{
+		Name:        "ruby",
+		DisplayName: "Ruby",
+		Extensions:  []string{".rb", ".rake"},
+		Filenames:   []string{"Rakefile", "Gemfile"},
+		NewParser:   func() parser.Parser { return parser.NewRubyParser() },
+		SyntaxCheck: &linter.RubySyntaxCheck,
+		Linters:     linter.RubyTools,
+	})
+
+	mustRegister(&Language{
+		Name:        "perl",
+		DisplayName: "Perl",
+		Extensions:  []string{".pl", ".pm"},
+		NewParser:   func() parser.Parser { return parser.NewPerlParser() },
+	})
+
+	mustRegister(&Language{
+		Name:        "r",
+		DisplayName: "R",
+		Extensions:  []string{".r"},
+		NewParser:   func() parser.Parser { return parser.NewRParser() },
+	})
+
+	mustRegister(&Language{
+		Name:        "yaml",
+		DisplayName: "YAML",
+		Extensions:  []string{".yaml", ".yml"},
+		NewParser:   func() parser.Parser { return parser.NewYAMLParser() },
+	})
+
+	mustRegister(&Language{
+		Name:        "toml",
+		DisplayName: "TOML",
+		Extensions:  []string{".toml"},
+		NewParser:   func() parser.Parser { return parser.NewTOMLParser() },
+	})
+
+	mustRegister(&Language{
+		Name:        "make",
+		DisplayName: "Make",
+		Extensions:  []string{".mk"},
+		Filenames:   []string{"Makefile", "makefile", "GNUmakefile"},
+		NewParser:   func() parser.Parser { return parser.NewMakefileParser() },
+	})
+}
@@ -2,7 +2,11 @@ package linter
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -13,7 +17,8 @@ type Tool struct {
 	Args       []string
 	InstallCmd string
 	InstallURL string
-	Languages  []string // "js", "py"
+	Languages  []string // "js", "py", "go", "rust"
+	Project    string   // Checks a whole project, found by this file, e.g. "Cargo.toml", instead of one path
 }
 
 // Available tools in order of preference
@@ -90,6 +95,93 @@ var PyTools = []Tool{
 	},
 }
 
+var RustTools = []Tool{
+	{
+		Name:       "rustfmt",
+		Command:    "rustfmt",
+		Args:       []string{"--check", "--edition", "2021"},
+		InstallCmd: "rustup component add rustfmt",
+		InstallURL: "https://github.com/rust-lang/rustfmt",
+		Languages:  []string{"rust"},
+	},
+	{
+		Name:       "Clippy",
+		Command:    "cargo",
+		Args:       []string{"clippy", "--quiet"},
+		InstallCmd: "rustup component add clippy",
+		InstallURL: "https://github.com/rust-lang/rust-clippy",
+		Languages:  []string{"rust"},
+		Project:    "Cargo.toml",
+	},
+}
+
+var JavaTools = []Tool{
+	{
+		Name:       "google-java-format",
+		Command:    "google-java-format",
+		Args:       []string{"--replace"},
+		InstallCmd: "brew install google-java-format",
+		InstallURL: "https://github.com/google/google-java-format",
+		Languages:  []string{"java"},
+	},
+}
+
+var KotlinTools = []Tool{
+	{
+		Name:       "ktlint",
+		Command:    "ktlint",
+		Args:       []string{"--format"},
+		InstallCmd: "brew install ktlint",
+		InstallURL: "https://pinterest.github.io/ktlint/",
+		Languages:  []string{"kotlin"},
+	},
+}
+
+var CTools = []Tool{
+	{
+		Name:       "clang-format",
+		Command:    "clang-format",
+		Args:       []string{"-i"},
+		InstallCmd: "apt install clang-format / brew install clang-format",
+		InstallURL: "https://clang.llvm.org/docs/ClangFormat.html",
+		Languages:  []string{"c", "cpp"},
+	},
+}
+
+var CSharpTools = []Tool{
+	{
+		Name:       "dotnet format",
+		Command:    "dotnet",
+		Args:       []string{"format", "whitespace", ".", "--folder", "--include"},
+		InstallCmd: "Included with the .NET SDK",
+		InstallURL: "https://learn.microsoft.com/dotnet/core/tools/dotnet-format",
+		Languages:  []string{"csharp"},
+	},
+}
+
+// shellcheck only reports problems; it never rewrites the script
+var ShellTools = []Tool{
+	{
+		Name:       "ShellCheck",
+		Command:    "shellcheck",
+		Args:       []string{},
+		InstallCmd: "brew install shellcheck",
+		InstallURL: "https://www.shellcheck.net/",
+		Languages:  []string{"shell"},
+	},
+}
+
+var RubyTools = []Tool{
+	{
+		Name:       "RuboCop",
+		Command:    "rubocop",
+		Args:       []string{"--autocorrect", "--format", "quiet"},
+		InstallCmd: "gem install rubocop",
+		InstallURL: "https://rubocop.org/",
+		Languages:  []string{"ruby"},
+	},
+}
+
 // Syntax checkers (just verify code is valid)
 var JSSyntaxCheck = Tool{
 	Name:       "Node.js",
@@ -118,6 +210,56 @@ var GoSyntaxCheck = Tool{
 	Languages:  []string{"go"},
 }
 
+// rustfmt parses the file and prints the formatted result without
+// touching it, failing only on syntax errors
+var RustSyntaxCheck = Tool{
+	Name:       "rustfmt",
+	Command:    "rustfmt",
+	Args:       []string{"--edition", "2021", "--emit", "stdout"},
+	InstallCmd: "rustup component add rustfmt",
+	InstallURL: "https://github.com/rust-lang/rustfmt",
+	Languages:  []string{"rust"},
+}
+
+// google-java-format prints the formatted file to stdout and fails on
+// syntax errors. Kotlin has no checker that works without the whole build.
+var JavaSyntaxCheck = Tool{
+	Name:       "google-java-format",
+	Command:    "google-java-format",
+	Args:       []string{},
+	InstallCmd: "brew install google-java-format",
+	InstallURL: "https://github.com/google/google-java-format",
+	Languages:  []string{"java"},
+}
+
+// The shells parse without executing when given -n
+var ShellSyntaxCheck = Tool{
+	Name:       "bash",
+	Command:    "bash",
+	Args:       []string{"-n"},
+	InstallCmd: "Included with most systems",
+	InstallURL: "https://www.gnu.org/software/bash/",
+	Languages:  []string{"shell"},
+}
+
+var ZshSyntaxCheck = Tool{
+	Name:       "zsh",
+	Command:    "zsh",
+	Args:       []string{"-n"},
+	InstallCmd: "brew install zsh",
+	InstallURL: "https://www.zsh.org/",
+	Languages:  []string{"zsh"},
+}
+
+var RubySyntaxCheck = Tool{
+	Name:       "Ruby",
+	Command:    "ruby",
+	Args:       []string{"-c"},
+	InstallCmd: "Download from https://www.ruby-lang.org/",
+	InstallURL: "https://www.ruby-lang.org/",
+	Languages:  []string{"ruby"},
+}
+
 // Result of running a tool
 type Result struct {
 	Tool    string
@@ -128,55 +270,90 @@ type Result struct {
 
 // AvailableTools checks which tools are installed
 type AvailableTools struct {
-	JSLinter     *Tool
-	PyLinter     *Tool
-	GoLinter     *Tool
-	JSSyntax     bool
-	PySyntax     bool
-	GoSyntax     bool
+	Linters      map[string]*Tool // Preferred installed linter per language
+	Syntax       map[string]bool  // Whether each language's syntax checker is installed
+	Installed    map[string]bool  // Whether each linter command is installed
 	MissingTools []Tool
 }
 
-// DetectTools checks what linting tools are available
-func DetectTools() AvailableTools {
-	result := AvailableTools{}
+// DetectTools checks which of each language's linters and syntax
+// checkers are installed. Linters are listed in order of preference.
+func DetectTools(linters map[string][]Tool, syntax map[string]Tool) AvailableTools {
+	result := AvailableTools{
+		Linters:   make(map[string]*Tool),
+		Syntax:    make(map[string]bool),
+		Installed: make(map[string]bool),
+	}
 
-	// Check JS linters
-	for i := range JSTools {
-		if isAvailable(JSTools[i].Command) {
-			result.JSLinter = &JSTools[i]
-			break
-		} else {
-			result.MissingTools = append(result.MissingTools, JSTools[i])
+	// Check linters; those preferred over the first installed one are
+	// reported as missing
+	for _, lang := range sortedLanguages(linters) {
+		tools := linters[lang]
+		for i := range tools {
+			installed := isAvailable(tools[i].Command)
+			result.Installed[tools[i].Command] = installed
+			switch {
+			case result.Linters[lang] != nil:
+			case installed:
+				result.Linters[lang] = &tools[i]
+			default:
+				result.MissingTools = append(result.MissingTools, tools[i])
+			}
 		}
 	}
 
-	// Check Python linters
-	for i := range PyTools {
-		if isAvailable(PyTools[i].Command) {
-			result.PyLinter = &PyTools[i]
-			break
-		} else {
-			result.MissingTools = append(result.MissingTools, PyTools[i])
+	// Check syntax validators
+	for lang, tool := range syntax {
+		result.Syntax[lang] = isAvailable(tool.Command)
+	}
+
+	return result
+}
+
+// Pick returns the first installed tool in tools, or nil if none is
+func (a AvailableTools) Pick(tools []Tool) *Tool {
+	for i := range tools {
+		if a.Installed[tools[i].Command] {
+			return &tools[i]
 		}
 	}
+	return nil
+}
 
-	// Check Go linters
-	for i := range GoTools {
-		if isAvailable(GoTools[i].Command) {
-			result.GoLinter = &GoTools[i]
-			break
-		} else {
-			result.MissingTools = append(result.MissingTools, GoTools[i])
+// Prefer returns tools with those named in order moved to the front, in
+// that order. Names match a tool's Name or Command, ignoring case.
+func Prefer(tools []Tool, order []string) []Tool {
+	var front, rest []Tool
+	used := make([]bool, len(tools))
+	for _, name := range order {
+		for i, t := range tools {
+			if !used[i] && t.Is(name) {
+				front = append(front, t)
+				used[i] = true
+			}
 		}
 	}
+	for i, t := range tools {
+		if !used[i] {
+			rest = append(rest, t)
+		}
+	}
+	return append(front, rest...)
+}
 
-	// Check syntax validators
-	result.JSSyntax = isAvailable(JSSyntaxCheck.Command)
-	result.PySyntax = isAvailable(PySyntaxCheck.Command)
-	result.GoSyntax = isAvailable(GoSyntaxCheck.Command)
+// Is reports whether name refers to the tool by name or command
+func (t Tool) Is(name string) bool {
+	return strings.EqualFold(name, t.Name) || strings.EqualFold(name, t.Command)
+}
 
-	return result
+// sortedLanguages returns the languages with linters in a stable order
+func sortedLanguages(linters map[string][]Tool) []string {
+	langs := make([]string, 0, len(linters))
+	for lang := range linters {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
 }
 
 // isAvailable checks if a command is in PATH
@@ -185,28 +362,22 @@ func isAvailable(cmd string) bool {
 	return err == nil
 }
 
-// CheckSyntax verifies a file has valid syntax
-func CheckSyntax(path string, isPython bool, isGo bool) Result {
-	var tool Tool
-	if isPython {
-		tool = PySyntaxCheck
-	} else if isGo {
-		tool = GoSyntaxCheck
-	} else {
-		tool = JSSyntaxCheck
-	}
-
-	if !isAvailable(tool.Command) {
+// CheckSyntax verifies a file has valid syntax using tool. A nil tool
+// means the language has no checker.
+func CheckSyntax(path string, tool *Tool) Result {
+	if tool == nil || !isAvailable(tool.Command) {
+		name := "none"
+		if tool != nil {
+			name = tool.Name
+		}
 		return Result{
-			Tool:    tool.Name,
+			Tool:    name,
 			Success: true, // Skip if not available
 			Output:  "syntax check skipped (tool not available)",
 		}
 	}
 
-	args := append(tool.Args, path)
-	cmd := exec.Command(tool.Command, args...)
-	output, err := cmd.CombinedOutput()
+	output, err := run(*tool, path)
 
 	if err != nil {
 		return Result{
@@ -224,17 +395,8 @@ func CheckSyntax(path string, isPython bool, isGo bool) Result {
 	}
 }
 
-// RunLinter runs the appropriate linter on a file
-func RunLinter(path string, isPython bool, isGo bool, tools AvailableTools) Result {
-	var tool *Tool
-	if isPython {
-		tool = tools.PyLinter
-	} else if isGo {
-		tool = tools.GoLinter
-	} else {
-		tool = tools.JSLinter
-	}
-
+// RunLinter runs tool on a file. A nil tool means no linter is installed.
+func RunLinter(path string, tool *Tool) Result {
 	if tool == nil {
 		return Result{
 			Tool:    "none",
@@ -243,9 +405,7 @@ func RunLinter(path string, isPython bool, isGo bool, tools AvailableTools) Resu
 		}
 	}
 
-	args := append(tool.Args, path)
-	cmd := exec.Command(tool.Command, args...)
-	output, err := cmd.CombinedOutput()
+	output, err := run(*tool, path)
 
 	if err != nil {
 		// Some linters return non-zero for warnings, check output
@@ -267,13 +427,48 @@ func RunLinter(path string, isPython bool, isGo bool, tools AvailableTools) Resu
 	}
 }
 
+// ProjectDir returns the directory a project-wide tool checks path in:
+// the nearest one above it holding the tool's project file, or the file's
+// own directory if there is none. It returns "" for tools that check
+// single files.
+func ProjectDir(path string, tool *Tool) string {
+	if tool == nil || tool.Project == "" {
+		return ""
+	}
+	start := filepath.Dir(path)
+	if abs, err := filepath.Abs(start); err == nil {
+		start = abs
+	}
+	for dir := start; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, tool.Project)); err == nil {
+			return dir
+		}
+		if filepath.Dir(dir) == dir {
+			return start
+		}
+	}
+}
+
+// run executes a tool against path and returns its combined output
+func run(tool Tool, path string) ([]byte, error) {
+	args := append([]string{}, tool.Args...)
+	cmd := exec.Command(tool.Command)
+	if dir := ProjectDir(path, &tool); dir != "" {
+		cmd.Dir = dir
+	} else {
+		args = append(args, path)
+	}
+	cmd.Args = append(cmd.Args, args...)
+	return cmd.CombinedOutput()
+}
+
 // PrintMissingTools prints suggestions for installing missing tools
-func PrintMissingTools(tools AvailableTools, verbose bool) {
+func PrintMissingTools(w io.Writer, tools AvailableTools, verbose bool) {
 	if !verbose || len(tools.MissingTools) == 0 {
 		return
 	}
 
-	fmt.Println("\nOptional linters not found (install for better results):")
+	fmt.Fprintln(w, "\nOptional linters not found (install for better results):")
 
 	seen := make(map[string]bool)
 	for _, t := range tools.MissingTools {
@@ -281,7 +476,7 @@ func PrintMissingTools(tools AvailableTools, verbose bool) {
 			continue
 		}
 		seen[t.Name] = true
-		fmt.Printf("  %s: %s\n", t.Name, t.InstallCmd)
+		fmt.Fprintf(w, "  %s: %s\n", t.Name, t.InstallCmd)
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 }
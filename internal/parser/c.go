@@ -0,0 +1,37 @@
+package parser
+
+var cSyntaxC = cSyntax{
+	cppLiterals:  true,
+	preprocessor: true,
+}
+
+// CParser parses C and C++ files for comments. Preprocessor lines are
+// code, and C++ raw strings and encoding prefixes are understood.
+type CParser struct {
+	lang string
+}
+
+// NewCParser creates a new C parser
+func NewCParser() *CParser {
+	return &CParser{lang: "c"}
+}
+
+// NewCppParser creates a new C++ parser
+func NewCppParser() *CParser {
+	return &CParser{lang: "cpp"}
+}
+
+// Tokenize splits C or C++ content into lexical tokens
+func (p *CParser) Tokenize(content string) []Token {
+	return tokenizeC(content, cSyntaxC)
+}
+
+// Parse extracts all comments from C or C++ content
+func (p *CParser) Parse(content string) ParseResult {
+	return parseCStyle(content, p.Tokenize(content), p.lang, docCommentKind)
+}
+
+// ReplaceComment replaces a comment in the content with new text
+func (p *CParser) ReplaceComment(content string, comment Comment, newText string) string {
+	return replaceCStyleComment(content, comment, newText)
+}
@@ -0,0 +1,509 @@
+package parser
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// cOperators lists multi-character operators of the C family, longest
+// first. One list serves every language, since tokens only need to split
+// the same source the same way every time, not match each compiler.
+var cOperators = []string{
+	">>>=", "<<=", ">>=", ">>>", "<=>", "...", "..=", "->*", "?.[",
+	"::", "->", "=>", "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "++", "--",
+	"+=", "-=", "*=", "/=", "%=", "^=", "&=", "|=", "<<", ">>", "..", "!!",
+}
+
+// cSyntax configures the C-family lexer for one language
+type cSyntax struct {
+	nestedComments   bool // /* /* */ */ nests (Rust, Kotlin)
+	multilineStrings bool // "..." may span lines (Rust)
+	rustLiterals     bool // r#"..."# raw strings, b/c prefixes, 'lifetimes
+	textBlocks       bool // """...""" text blocks (Java) and raw strings (Kotlin, C#)
+	rawTextBlocks    bool // Text blocks have no escapes (Kotlin, C#)
+	templates        bool // "${...}" string templates (Kotlin)
+	cppLiterals      bool // R"delim(...)delim" raw strings and L/u/U/u8 prefixes (C, C++)
+	csStrings        bool // @"..." verbatim and $"...{expr}..." interpolated strings (C#)
+	preprocessor     bool // #directive lines (C, C++, C#)
+}
+
+// freeTextDirectives are preprocessor directives whose argument is free
+// text, so quotes in it must not open a literal
+var freeTextDirectives = map[string]bool{
+	"error":     true,
+	"warning":   true,
+	"region":    true,
+	"endregion": true,
+}
+
+// cLexer splits C-family source into tokens according to its syntax
+type cLexer struct {
+	syntax cSyntax
+	src    string
+	pos    int
+	tokens []Token
+}
+
+// tokenizeC returns the tokens in C-family content
+func tokenizeC(content string, syntax cSyntax) []Token {
+	l := &cLexer{syntax: syntax, src: content}
+	l.lex()
+	return l.tokens
+}
+
+func (l *cLexer) emit(kind TokenKind, start int) {
+	l.tokens = append(l.tokens, Token{Kind: kind, Start: start, End: l.pos})
+}
+
+// peek returns the byte n positions ahead, or 0 past the end
+func (l *cLexer) peek(n int) byte {
+	if l.pos+n < len(l.src) {
+		return l.src[l.pos+n]
+	}
+	return 0
+}
+
+func (l *cLexer) lex() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		start := l.pos
+
+		switch {
+		case isSpaceByte(c):
+			l.pos++
+
+		case c == '/' && l.peek(1) == '/':
+			l.pos = lineEnd(l.src, l.pos)
+			l.emit(TokenComment, start)
+
+		case c == '/' && l.peek(1) == '*':
+			l.lexBlockComment()
+			l.emit(TokenComment, start)
+
+		case c == '"' && l.syntax.textBlocks && strings.HasPrefix(l.src[l.pos:], `"""`):
+			l.lexTextBlock()
+			l.emit(TokenString, start)
+
+		case c == '"':
+			l.lexQuoted('"', l.syntax.multilineStrings)
+			l.emit(TokenString, start)
+
+		case (c == '$' || c == '@') && l.syntax.csStrings && l.lexCSharpString():
+			l.emit(TokenString, start)
+
+		case c == '#' && l.syntax.preprocessor && !isTrailing(l.src, l.pos):
+			l.lexPreprocessor()
+			l.emit(TokenCode, start)
+
+		case c == '\'':
+			if l.syntax.rustLiterals && l.isLifetime() {
+				l.pos++
+				l.lexIdent()
+				l.emit(TokenCode, start)
+			} else {
+				l.lexQuoted('\'', false)
+				l.emit(TokenString, start)
+			}
+
+		case isIdentByte(c) && !isDigitByte(c):
+			l.lexIdent()
+			if l.lexPrefixedString(start) {
+				l.emit(TokenString, start)
+			} else {
+				l.emit(TokenCode, start)
+			}
+
+		case isDigitByte(c):
+			l.lexNumber()
+			l.emit(TokenCode, start)
+
+		default:
+			l.pos += len(matchOperator(l.src, l.pos, cOperators))
+			l.emit(TokenCode, start)
+		}
+	}
+}
+
+// lexBlockComment scans a /* */ comment, honoring nesting when the
+// language allows it. Unterminated comments run to the end of the file.
+func (l *cLexer) lexBlockComment() {
+	if !l.syntax.nestedComments {
+		l.pos = blockEnd(l.src, l.pos+2, "*/")
+		return
+	}
+	depth := 0
+	for l.pos < len(l.src) {
+		switch {
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			depth++
+			l.pos += 2
+		case strings.HasPrefix(l.src[l.pos:], "*/"):
+			depth--
+			l.pos += 2
+			if depth == 0 {
+				return
+			}
+		default:
+			l.pos++
+		}
+	}
+}
+
+// lexQuoted scans a string or char literal with backslash escapes. Char
+// literals and single-line strings end at a newline so an unbalanced quote
+// can't swallow the file.
+func (l *cLexer) lexQuoted(quote byte, multiline bool) {
+	l.pos++
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\\':
+			l.pos += 2
+			continue
+		case c == quote:
+			l.pos++
+			return
+		case c == '\n' && !multiline:
+			return
+		case c == '$' && l.syntax.templates && l.peek(1) == '{':
+			l.pos++
+			l.skipBraced()
+			continue
+		}
+		l.pos++
+	}
+	l.pos = len(l.src)
+}
+
+// lexTextBlock scans a """ text block. Kotlin raw strings have no escapes
+// and may end with extra quotes, as in """a""""
+func (l *cLexer) lexTextBlock() {
+	l.pos += 3
+	for l.pos < len(l.src) {
+		switch {
+		case l.src[l.pos] == '\\' && !l.syntax.rawTextBlocks:
+			l.pos += 2
+		case strings.HasPrefix(l.src[l.pos:], `"""`):
+			l.pos += 3
+			for l.pos < len(l.src) && l.src[l.pos] == '"' {
+				l.pos++
+			}
+			return
+		case l.src[l.pos] == '$' && l.syntax.templates && l.peek(1) == '{':
+			l.pos++
+			l.skipBraced()
+		default:
+			l.pos++
+		}
+	}
+	l.pos = len(l.src)
+}
+
+// skipBraced skips a {...} template or interpolation expression starting
+// at '{', including any strings nested inside it
+func (l *cLexer) skipBraced() {
+	l.pos++
+	depth := 0
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == '"' && l.syntax.textBlocks && strings.HasPrefix(l.src[l.pos:], `"""`):
+			l.lexTextBlock()
+		case c == '"' || c == '\'':
+			l.lexQuoted(c, c == '"' && l.syntax.multilineStrings)
+		case (c == '$' || c == '@') && l.syntax.csStrings && l.lexCSharpString():
+		case c == '{':
+			depth++
+			l.pos++
+		case c == '}':
+			l.pos++
+			if depth == 0 {
+				return
+			}
+			depth--
+		default:
+			l.pos++
+		}
+	}
+}
+
+// lexCSharpString scans a C# string with $ and @ prefixes: $"...",
+// @"...", $@"..." and $"""...""". It reports false, leaving the position
+// untouched, when the prefix isn't followed by a string.
+func (l *cLexer) lexCSharpString() bool {
+	pos := l.pos
+	interpolated, verbatim := false, false
+	for pos < len(l.src) && (l.src[pos] == '$' || l.src[pos] == '@') {
+		interpolated = interpolated || l.src[pos] == '$'
+		verbatim = verbatim || l.src[pos] == '@'
+		pos++
+	}
+	if pos >= len(l.src) || l.src[pos] != '"' {
+		return false
+	}
+	l.pos = pos
+
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		// Raw string literal; interpolation holes can't hold """
+		l.lexTextBlock()
+		return true
+	}
+
+	l.pos++
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case verbatim && c == '"' && l.peek(1) == '"':
+			l.pos += 2
+		case c == '"':
+			l.pos++
+			return true
+		case !verbatim && c == '\\':
+			l.pos += 2
+		case !verbatim && c == '\n':
+			return true
+		case interpolated && c == '{' && l.peek(1) == '{':
+			l.pos += 2
+		case interpolated && c == '{':
+			l.skipBraced()
+		default:
+			l.pos++
+		}
+	}
+	l.pos = len(l.src)
+	return true
+}
+
+// lexPreprocessor scans a #directive name. Free-text directives such as
+// #error and #region take the rest of the line with them.
+func (l *cLexer) lexPreprocessor() {
+	l.pos++
+	for l.pos < len(l.src) && (l.src[l.pos] == ' ' || l.src[l.pos] == '\t') {
+		l.pos++
+	}
+	nameStart := l.pos
+	l.lexIdent()
+	if freeTextDirectives[l.src[nameStart:l.pos]] {
+		l.pos = lineEnd(l.src, l.pos)
+	}
+}
+
+// lexIdent scans identifier bytes
+func (l *cLexer) lexIdent() {
+	for l.pos < len(l.src) && isIdentByte(l.src[l.pos]) {
+		l.pos++
+	}
+}
+
+// lexNumber scans a numeric literal including suffixes and exponents
+func (l *cLexer) lexNumber() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if isIdentByte(c) || (c == '.' && isDigitByte(l.peek(1))) || c == '\'' && l.isDigitSeparator() {
+			l.pos++
+			continue
+		}
+		if (c == '+' || c == '-') && (l.src[l.pos-1] == 'e' || l.src[l.pos-1] == 'E') &&
+			!strings.HasPrefix(strings.ToLower(l.src[:l.pos]), "0x") {
+			l.pos++
+			continue
+		}
+		break
+	}
+}
+
+// isDigitSeparator reports whether the quote at the current position is a
+// C++14 digit separator such as 1'000'000
+func (l *cLexer) isDigitSeparator() bool {
+	return !l.syntax.rustLiterals && isDigitByte(l.src[l.pos-1]) && isDigitByte(l.peek(1))
+}
+
+// isLifetime reports whether the quote at the current position starts a
+// Rust lifetime or label ('a, 'static) rather than a char literal ('a')
+func (l *cLexer) isLifetime() bool {
+	next := l.peek(1)
+	if next == '\\' || !(isIdentByte(next) && !isDigitByte(next)) {
+		return false
+	}
+	_, size := utf8.DecodeRuneInString(l.src[l.pos+1:])
+	return l.peek(1+size) != '\''
+}
+
+// lexPrefixedString checks whether the identifier just scanned from start
+// is a string prefix (such as Rust's r or br, or C++'s u8 or R) and scans the literal
+// that follows. It reports false when the identifier is ordinary code.
+func (l *cLexer) lexPrefixedString(start int) bool {
+	prefix := l.src[start:l.pos]
+	next := l.peek(0)
+
+	switch {
+	case l.syntax.rustLiterals:
+		return l.lexRustPrefixed(prefix)
+
+	case l.syntax.cppLiterals && next == '"' && strings.HasSuffix(prefix, "R"):
+		switch prefix {
+		case "R", "LR", "uR", "UR", "u8R":
+			l.lexCppRawString()
+			return true
+		}
+
+	case l.syntax.cppLiterals && (next == '"' || next == '\''):
+		switch prefix {
+		case "L", "u", "U", "u8":
+			l.lexQuoted(next, false)
+			return true
+		}
+	}
+	return false
+}
+
+// lexRustPrefixed scans byte, C and raw string literals after their prefix
+func (l *cLexer) lexRustPrefixed(prefix string) bool {
+	switch prefix {
+	case "b", "c":
+		switch l.peek(0) {
+		case '"':
+			l.lexQuoted('"', true)
+			return true
+		case '\'':
+			l.lexQuoted('\'', false)
+			return true
+		}
+	case "r", "br", "cr":
+		hashes := 0
+		for l.peek(hashes) == '#' {
+			hashes++
+		}
+		if l.peek(hashes) != '"' {
+			if prefix == "r" && hashes == 1 {
+				// Raw identifier such as r#type
+				l.pos++
+				l.lexIdent()
+			}
+			return false
+		}
+		l.pos += hashes + 1
+		l.pos = blockEnd(l.src, l.pos, `"`+strings.Repeat("#", hashes))
+		return true
+	}
+	return false
+}
+
+// lexCppRawString scans R"delim(...)delim" with the position at the quote
+func (l *cLexer) lexCppRawString() {
+	open := strings.IndexByte(l.src[l.pos:], '(')
+	if open < 0 {
+		l.lexQuoted('"', false)
+		return
+	}
+	delim := l.src[l.pos+1 : l.pos+open]
+	l.pos = blockEnd(l.src, l.pos+open+1, ")"+delim+`"`)
+}
+
+// docCommentKind recognizes Doxygen-style doc comments shared by Rust,
+// C, C++ and C#: ///, //!, /** */ and /*! */
+func docCommentKind(original string) CommentKind {
+	switch {
+	case strings.HasPrefix(original, "//!"), strings.HasPrefix(original, "/*!"):
+		return KindDoc
+	case strings.HasPrefix(original, "///") && !strings.HasPrefix(original, "////"):
+		return KindDoc
+	case strings.HasPrefix(original, "/**") && !strings.HasPrefix(original, "/***") && original != "/**/":
+		return KindDoc
+	}
+	return KindStandalone
+}
+
+// parseCStyle builds comments from C-family tokens. docKind classifies
+// the language's doc comment markers from the comment's original text.
+func parseCStyle(content string, tokens []Token, lang string, docKind func(original string) CommentKind) ParseResult {
+	var comments []Comment
+
+	for _, tok := range tokens {
+		if tok.Kind != TokenComment {
+			continue
+		}
+		original := tok.Text(content)
+		isBlock := strings.HasPrefix(original, "/*")
+
+		kind := docKind(original)
+
+		var text string
+		switch {
+		case kind == KindDoc && isBlock:
+			text, _ = splitDocBlock(original)
+		case isBlock:
+			text = strings.TrimPrefix(original, "/*")
+			text = strings.TrimSuffix(text, "*/")
+			text = strings.TrimLeft(text, "*!")
+		default:
+			text = strings.TrimLeft(original[2:], "/!")
+		}
+
+		comments = append(comments, Comment{
+			Text:       strings.TrimSpace(text),
+			Start:      tok.Start,
+			End:        tok.End,
+			LineNumber: countLines(content[:tok.Start]) + 1,
+			IsBlock:    isBlock,
+			Original:   original,
+			Kind:       kind,
+		})
+	}
+
+	classifyComments(content, comments, firstCodeStart(content, tokens), Directives(lang))
+
+	return ParseResult{
+		Content:  content,
+		Comments: comments,
+	}
+}
+
+// splitDocBlock splits a /** */ doc comment into its description and the
+// lines holding block tags such as @param and @return. The description
+// has the leading " * " decoration removed from each line.
+func splitDocBlock(original string) (description string, tagLines []string) {
+	inner := strings.TrimSuffix(strings.TrimPrefix(original, "/**"), "*/")
+	lines := strings.Split(inner, "\n")
+
+	var desc []string
+	for i, line := range lines {
+		text := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "*"))
+		if strings.HasPrefix(text, "@") {
+			tagLines = lines[i:]
+			break
+		}
+		desc = append(desc, text)
+	}
+	return strings.TrimSpace(strings.Join(desc, "\n")), tagLines
+}
+
+// replaceDocBlock rewrites the description of a multi-line /** */ doc
+// comment while keeping its @param, @return and other tag lines. Doc
+// comments without tags are replaced or removed whole.
+func replaceDocBlock(content string, comment Comment, newText string) string {
+	_, tagLines := splitDocBlock(comment.Original)
+	if len(tagLines) == 0 || !strings.Contains(comment.Original, "\n") || !isIntact(content, comment) {
+		return replaceCStyleComment(content, comment, newText)
+	}
+
+	// Reuse the decoration of the first tag line, e.g. "   * "
+	first := tagLines[0]
+	trimmed := strings.TrimLeft(first, " \t")
+	decoration := first[:len(first)-len(trimmed)]
+	if strings.HasPrefix(trimmed, "*") {
+		decoration += "* "
+	}
+
+	var b strings.Builder
+	b.WriteString("/**\n")
+	for _, line := range strings.Split(newText, "\n") {
+		if newText == "" {
+			break
+		}
+		b.WriteString(decoration + line + "\n")
+	}
+	b.WriteString(strings.Join(tagLines, "\n"))
+	b.WriteString("*/")
+
+	return content[:comment.Start] + b.String() + content[comment.End:]
+}
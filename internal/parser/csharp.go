@@ -0,0 +1,31 @@
+package parser
+
+var csharpSyntax = cSyntax{
+	textBlocks:    true,
+	rawTextBlocks: true,
+	csStrings:     true,
+	preprocessor:  true,
+}
+
+// CSharpParser parses C# files for comments, including /// XML doc comments
+type CSharpParser struct{}
+
+// NewCSharpParser creates a new C# parser
+func NewCSharpParser() *CSharpParser {
+	return &CSharpParser{}
+}
+
+// Tokenize splits C# content into lexical tokens
+func (p *CSharpParser) Tokenize(content string) []Token {
+	return tokenizeC(content, csharpSyntax)
+}
+
+// Parse extracts all comments from C# content
+func (p *CSharpParser) Parse(content string) ParseResult {
+	return parseCStyle(content, p.Tokenize(content), "csharp", docCommentKind)
+}
+
+// ReplaceComment replaces a comment in the content with new text
+func (p *CSharpParser) ReplaceComment(content string, comment Comment, newText string) string {
+	return replaceCStyleComment(content, comment, newText)
+}
@@ -0,0 +1,158 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// AllLanguages registers a directive for every language
+const AllLanguages = "*"
+
+// directives holds the directive patterns for each language ("js", "py",
+// "go", ...). Patterns are matched against a comment's original text,
+// delimiters included, so "//go:embed" and "// go:embed" can be told apart.
+var directives = map[string][]*regexp.Regexp{}
+
+func init() {
+	// Tool and editor markers that mean the same thing in every language
+	mustRegister(AllLanguages,
+		`@generated\b`,
+		`-\*-.*-\*-`,
+		`\bvim?:\s*set?\s`,
+		markerRe.String(),
+	)
+
+	mustRegister("go",
+		`^//(go|lint):`,
+		`^//(line|export|extern|sys) `,
+		`^/\*line `,
+		`^//\s*\+build`,
+		`^//\s*nolint`,
+		`^//\s*#nosec`,
+		`^//(revive|exhaustive):`,
+		`^// Code generated .* DO NOT EDIT\.$`,
+	)
+
+	mustRegister("js",
+		`^/[/*]\s*(eslint|tslint|stylelint)`,
+		`^/[/*]\s*(biome|deno-lint|oxlint)-ignore`,
+		`^/\*\s*(global|jshint|jslint)\s`,
+		`^//\s*@ts-(ignore|expect-error|nocheck|check)`,
+		`^///\s*<(reference|amd-module|amd-dependency)`,
+		`^/[/*]\s*(prettier-ignore|istanbul ignore|c8 ignore|v8 ignore|@flow|@jsx|@refresh)`,
+		`^/\*\s*[#@]__(PURE|NO_SIDE_EFFECTS)__\s*\*/$`,
+		`^/\*\s*webpack[A-Z]\w*:`,
+		`^/[/*]\*?\s*@(vitest|jest)-environment`,
+		`^//\s*[#@]\s*sourceMappingURL=`,
+	)
+
+	mustRegister("rust",
+		`^//\s*SAFETY:`,
+		`^//~`,
+		`^//@\s`,
+		`^//\s*ignore-tidy`,
+	)
+
+	for _, lang := range []string{"java", "kotlin"} {
+		mustRegister(lang,
+			`^//\s*noinspection`,
+			`^/[/*]\s*@formatter:(on|off)`,
+			`^/[/*]\s*(CHECKSTYLE|checkstyle):`,
+			`^/[/*]\s*(NOSONAR|NOPMD)`,
+			`^/[/*]\s*language=`,
+			`^/[/*]\s*ktlint-disable`,
+			`//\s*\$NON-NLS-\d+\$`,
+		)
+	}
+
+	for _, lang := range []string{"c", "cpp"} {
+		mustRegister(lang,
+			`^/[/*]\s*NOLINT`,
+			`^/[/*]\s*clang-format (on|off)`,
+			`^/[/*]\s*(IWYU pragma|cppcheck-suppress|coverity\[|LCOV_EXCL_)`,
+			`(?i)^/[/*]\s*fall(s|-)?\s*thr(ough|u)`,
+			`^/\*\s*(NOTREACHED|ARGSUSED|VARARGS\d*|LINTLIBRARY)\s*\*/$`,
+		)
+	}
+
+	mustRegister("csharp",
+		`^//\s*<auto-generated`,
+		`(?i)^//\s*resharper (disable|restore)`,
+		`^//\s*dotcover (disable|enable)`,
+	)
+
+	for _, lang := range []string{"shell", "zsh"} {
+		mustRegister(lang,
+			`^#\s*shellcheck\s`,
+			`^#\s*shfmt:`,
+		)
+	}
+
+	mustRegister("ruby",
+		`^#\s*(frozen_string_literal|encoding|coding|warn_indent|shareable_constant_value):`,
+		`^#\s*(rubocop|standard):(disable|enable|todo)`,
+		`^#\s*typed:\s*\w+`,
+		`^#\s*:nocov:`,
+	)
+
+	mustRegister("perl",
+		`^##?\s*(no|use) critic`,
+		`^#\s*perltidy`,
+		`^#\s*line \d+`,
+	)
+
+	mustRegister("r",
+		`^#'\s*@`,
+		`^#\s*(nolint|nocov|styler:)`,
+	)
+
+	mustRegister("yaml",
+		`^#\s*yamllint (disable|enable)`,
+		`^#\s*yaml-language-server:`,
+		`^#\s*(prettier-ignore|renovate:|@schema)`,
+	)
+
+	mustRegister("toml",
+		`^#:schema\s`,
+		`^#\s*taplo:`,
+	)
+
+	mustRegister("py",
+		`^#.*coding[:=]`,
+		`^#\s*(type|pragma|pylint|mypy|pyright|pytype|isort|ruff|flake8):`,
+		`^#\s*fmt:\s*(on|off|skip)`,
+		`^#.*\b(noqa|nosec)\b`,
+	)
+}
+
+// RegisterDirective marks comments in lang whose original text matches
+// expr as directives, so they are never rewritten. Use AllLanguages to
+// register a pattern everywhere. Registration must happen before parsing.
+func RegisterDirective(lang, expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid directive pattern %q: %w", expr, err)
+	}
+	directives[lang] = append(directives[lang], re)
+	return nil
+}
+
+// Directives returns the directive patterns that apply to lang
+func Directives(lang string) []*regexp.Regexp {
+	var patterns []*regexp.Regexp
+	patterns = append(patterns, directives[AllLanguages]...)
+	return append(patterns, directives[lang]...)
+}
+
+// IsDirective reports whether a comment's original text is a directive in lang
+func IsDirective(lang, original string) bool {
+	return matchesAny(original, Directives(lang))
+}
+
+func mustRegister(lang string, exprs ...string) {
+	for _, expr := range exprs {
+		if err := RegisterDirective(lang, expr); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -1,11 +1,18 @@
 package parser
 
 import (
-	"regexp"
+	"go/ast"
+	goparser "go/parser"
+	"go/scanner"
+	gotoken "go/token"
 	"strings"
 )
 
-// GoParser parses Go files for comments
+// cgoImport is the target of a cgo preamble, which is C code rather than prose
+const cgoImport = `import "C"`
+
+// GoParser parses Go files for comments using the standard library's
+// scanner and parser, so comment positions are exact
 type GoParser struct{}
 
 // NewGoParser creates a new Go parser
@@ -13,90 +20,270 @@ func NewGoParser() *GoParser {
 	return &GoParser{}
 }
 
-var (
-	// Match single-line comments: // ...
-	goLineCommentRe = regexp.MustCompile(`(?m)//[^\n]*`)
-	// Match block comments: /* ... */
-	goBlockCommentRe = regexp.MustCompile(`(?s)/\*.*?\*/`)
-)
+// Tokenize splits Go content into lexical tokens using go/scanner
+func (p *GoParser) Tokenize(content string) []Token {
+	var tokens []Token
+
+	fset := gotoken.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(content))
+	var s scanner.Scanner
+	s.Init(file, []byte(content), nil, scanner.ScanComments)
+
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == gotoken.EOF {
+			break
+		}
+		// Skip semicolons inserted automatically at line ends
+		if tok == gotoken.SEMICOLON && lit == "\n" {
+			continue
+		}
+
+		start := file.Offset(pos)
+		var end int
+		kind := TokenCode
+		switch {
+		case tok == gotoken.COMMENT:
+			kind = TokenComment
+			end = goCommentEnd(content, start)
+		case tok == gotoken.STRING && content[start] == '`':
+			// Raw strings have carriage returns stripped from lit
+			kind = TokenString
+			end = blockEnd(content, start+1, "`")
+		case tok == gotoken.STRING || tok == gotoken.CHAR:
+			kind = TokenString
+			end = start + len(lit)
+		case lit != "":
+			end = start + len(lit)
+		default:
+			end = start + len(tok.String())
+		}
+		tokens = append(tokens, Token{Kind: kind, Start: start, End: end})
+	}
+
+	return tokens
+}
 
-// Parse extracts all comments from Go content
+// Parse extracts all comments from Go content. When the file parses, each
+// comment comes from an ast.CommentGroup and records the declaration it
+// documents; otherwise comments are taken straight from the scanner.
 func (p *GoParser) Parse(content string) ParseResult {
-	var comments []Comment
+	fset := gotoken.NewFileSet()
+	file, err := goparser.ParseFile(fset, "", content, goparser.ParseComments|goparser.SkipObjectResolution)
+	if err != nil || file == nil {
+		return p.parseTokens(content)
+	}
 
-	// Find line comments
-	for _, match := range goLineCommentRe.FindAllStringIndex(content, -1) {
-		start, end := match[0], match[1]
-		original := content[start:end]
-		text := strings.TrimPrefix(original, "//")
-		text = strings.TrimSpace(text)
-		lineNum := strings.Count(content[:start], "\n") + 1
-
-		comments = append(comments, Comment{
-			Text:       text,
-			Start:      start,
-			End:        end,
-			LineNumber: lineNum,
-			IsBlock:    false,
-			Original:   original,
-		})
-	}
-
-	// Find block comments
-	for _, match := range goBlockCommentRe.FindAllStringIndex(content, -1) {
-		start, end := match[0], match[1]
-		original := content[start:end]
-		text := strings.TrimPrefix(original, "/*")
-		text = strings.TrimSuffix(text, "*/")
-		text = strings.TrimSpace(text)
-		lineNum := strings.Count(content[:start], "\n") + 1
+	targets := goCommentTargets(file)
 
-		comments = append(comments, Comment{
-			Text:       text,
-			Start:      start,
-			End:        end,
-			LineNumber: lineNum,
-			IsBlock:    true,
-			Original:   original,
-		})
+	var comments []Comment
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			start := fset.Position(c.Slash).Offset
+			comment := newGoComment(content, start, targets[group])
+			switch comment.Target {
+			case "":
+			case cgoImport:
+				comment.Kind = KindDirective
+			default:
+				comment.Kind = KindDoc
+			}
+			comments = append(comments, comment)
+		}
 	}
 
+	classifyComments(content, comments, fset.Position(file.Package).Offset, Directives("go"))
+
 	return ParseResult{
 		Content:  content,
 		Comments: comments,
 	}
 }
 
-// ReplaceComment replaces a comment in the content with new text
-func (p *GoParser) ReplaceComment(content string, comment Comment, newText string) string {
-	var replacement string
-	if newText == "" {
-		// Remove the comment entirely
-		startIdx := comment.Start
-		endIdx := comment.End
-
-		// If comment is at start of line (only whitespace before it), remove the whole line
-		lineStart := startIdx
-		for lineStart > 0 && content[lineStart-1] != '\n' {
-			lineStart--
+// parseTokens extracts comments from the token stream of a file that does
+// not parse, without doc-comment attribution
+func (p *GoParser) parseTokens(content string) ParseResult {
+	var comments []Comment
+	tokens := p.Tokenize(content)
+	for _, tok := range tokens {
+		if tok.Kind == TokenComment {
+			comments = append(comments, newGoComment(content, tok.Start, ""))
 		}
-		prefix := content[lineStart:startIdx]
-		if strings.TrimSpace(prefix) == "" {
-			startIdx = lineStart
+	}
+	classifyComments(content, comments, firstCodeStart(content, tokens), Directives("go"))
+	return ParseResult{
+		Content:  content,
+		Comments: comments,
+	}
+}
+
+// newGoComment builds a Comment for the comment starting at start
+func newGoComment(content string, start int, target string) Comment {
+	end := goCommentEnd(content, start)
+	original := content[start:end]
+	isBlock := strings.HasPrefix(original, "/*")
+
+	var text string
+	if isBlock {
+		text = strings.TrimPrefix(original, "/*")
+		text = strings.TrimSuffix(text, "*/")
+	} else {
+		text = strings.TrimPrefix(original, "//")
+	}
+
+	return Comment{
+		Text:       strings.TrimSpace(text),
+		Start:      start,
+		End:        end,
+		LineNumber: countLines(content[:start]) + 1,
+		IsBlock:    isBlock,
+		Original:   original,
+		Target:     target,
+	}
+}
+
+// goCommentEnd returns the end offset of the comment starting at start.
+// Offsets are computed from the source because the scanner strips carriage
+// returns from comment text.
+func goCommentEnd(content string, start int) int {
+	if strings.HasPrefix(content[start:], "/*") {
+		return blockEnd(content, start+2, "*/")
+	}
+	return lineEnd(content, start)
+}
+
+// goCommentTargets maps each doc or line comment group to the declaration
+// it belongs to, such as "package main", "func (T) Run" or "field Name"
+func goCommentTargets(file *ast.File) map[*ast.CommentGroup]string {
+	targets := make(map[*ast.CommentGroup]string)
+	set := func(group *ast.CommentGroup, target string) {
+		if group != nil {
+			targets[group] = target
 		}
+	}
 
-		// Remove trailing newline if present
-		if endIdx < len(content) && content[endIdx] == '\n' {
-			endIdx++
+	set(file.Doc, "package "+file.Name.Name)
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			set(n.Doc, "func "+goFuncName(n))
+		case *ast.GenDecl:
+			if len(n.Specs) == 1 {
+				set(n.Doc, goSpecName(n.Tok, n.Specs[0]))
+			} else {
+				set(n.Doc, n.Tok.String())
+			}
+			for _, spec := range n.Specs {
+				name := goSpecName(n.Tok, spec)
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					set(s.Doc, name)
+					set(s.Comment, name)
+				case *ast.ValueSpec:
+					set(s.Doc, name)
+					set(s.Comment, name)
+				case *ast.ImportSpec:
+					set(s.Doc, name)
+					set(s.Comment, name)
+				}
+			}
+		case *ast.Field:
+			name := "field"
+			if len(n.Names) > 0 {
+				name = "field " + n.Names[0].Name
+			}
+			set(n.Doc, name)
+			set(n.Comment, name)
 		}
+		return true
+	})
+
+	return targets
+}
+
+// goFuncName formats a function or method name, e.g. "Parse" or "(*GoParser) Parse"
+func goFuncName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	return "(" + goTypeName(fn.Recv.List[0].Type) + ") " + fn.Name.Name
+}
 
-		return content[:startIdx] + content[endIdx:]
+// goTypeName formats a receiver type expression
+func goTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return "*" + goTypeName(t.X)
+	case *ast.IndexExpr:
+		return goTypeName(t.X)
+	case *ast.IndexListExpr:
+		return goTypeName(t.X)
 	}
+	return "?"
+}
 
-	if comment.IsBlock {
-		replacement = "/* " + newText + " */"
-	} else {
-		replacement = "// " + newText
+// goSpecName formats the declaration a spec introduces
+func goSpecName(tok gotoken.Token, spec ast.Spec) string {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return "type " + s.Name.Name
+	case *ast.ValueSpec:
+		return tok.String() + " " + s.Names[0].Name
+	case *ast.ImportSpec:
+		return "import " + s.Path.Value
 	}
-	return content[:comment.Start] + replacement + content[comment.End:]
+	return tok.String()
+}
+
+// Enclosing returns the top-level declaration containing each comment,
+// named as doc-comment targets are, e.g. "func (T) Run" or "type Config"
+func (p *GoParser) Enclosing(content string, comments []Comment) []string {
+	fset := gotoken.NewFileSet()
+	file, err := goparser.ParseFile(fset, "", content, goparser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	// Top-level declarations are in source order and do not overlap
+	type decl struct {
+		start, end int
+		name       string
+	}
+	var decls []decl
+	add := func(node ast.Node, name string) {
+		decls = append(decls, decl{fset.Position(node.Pos()).Offset, fset.Position(node.End()).Offset, name})
+	}
+	for _, d := range file.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			add(d, "func "+goFuncName(d))
+		case *ast.GenDecl:
+			if len(d.Specs) == 1 {
+				add(d, goSpecName(d.Tok, d.Specs[0]))
+				continue
+			}
+			for _, spec := range d.Specs {
+				add(spec, goSpecName(d.Tok, spec))
+			}
+		}
+	}
+
+	names := make([]string, len(comments))
+	for i, c := range comments {
+		for _, d := range decls {
+			if d.start <= c.Start && c.Start < d.end {
+				names[i] = d.name
+				break
+			}
+		}
+	}
+	return names
+}
+
+// ReplaceComment replaces a comment in the content with new text
+func (p *GoParser) ReplaceComment(content string, comment Comment, newText string) string {
+	return replaceCStyleComment(content, comment, newText)
 }
@@ -0,0 +1,194 @@
+package parser
+
+import "strings"
+
+var (
+	shellSyntax = hashSyntax{
+		wordComments:  true,
+		quotes:        "'\"`",
+		rawSingle:     true,
+		heredocs:      true,
+		interpolation: "$(",
+	}
+	rubySyntax = hashSyntax{
+		quotes:          "'\"`",
+		heredocs:        true,
+		interpolation:   "#{",
+		specialVars:     true,
+		blockComments:   true,
+		endMarker:       true,
+		regexLiterals:   true,
+		percentLiterals: true,
+	}
+	perlSyntax = hashSyntax{
+		quotes:        "'\"`",
+		heredocs:      true,
+		specialVars:   true,
+		blockComments: true,
+		endMarker:     true,
+		regexLiterals: true,
+		quoteLike:     true,
+	}
+	rSyntax = hashSyntax{
+		quotes:     "'\"`",
+		rawStrings: true,
+	}
+	yamlSyntax = hashSyntax{
+		quotes: "'\"",
+		yaml:   true,
+	}
+	tomlSyntax = hashSyntax{
+		quotes:       "'\"",
+		tripleQuotes: true,
+	}
+	makeSyntax = hashSyntax{
+		makefile: true,
+	}
+)
+
+// HashParser parses languages whose line comments start with '#': shell
+// scripts, Ruby, Perl, R, YAML, TOML and Makefiles. Ruby =begin/=end
+// blocks and Perl POD are reported as block comments.
+type HashParser struct {
+	lang   string
+	syntax hashSyntax
+}
+
+// NewShellParser creates a parser for sh and bash scripts
+func NewShellParser() *HashParser {
+	return &HashParser{lang: "shell", syntax: shellSyntax}
+}
+
+// NewZshParser creates a parser for zsh scripts
+func NewZshParser() *HashParser {
+	return &HashParser{lang: "zsh", syntax: shellSyntax}
+}
+
+// NewRubyParser creates a new Ruby parser
+func NewRubyParser() *HashParser {
+	return &HashParser{lang: "ruby", syntax: rubySyntax}
+}
+
+// NewPerlParser creates a new Perl parser
+func NewPerlParser() *HashParser {
+	return &HashParser{lang: "perl", syntax: perlSyntax}
+}
+
+// NewRParser creates a new R parser
+func NewRParser() *HashParser {
+	return &HashParser{lang: "r", syntax: rSyntax}
+}
+
+// NewYAMLParser creates a new YAML parser
+func NewYAMLParser() *HashParser {
+	return &HashParser{lang: "yaml", syntax: yamlSyntax}
+}
+
+// NewTOMLParser creates a new TOML parser
+func NewTOMLParser() *HashParser {
+	return &HashParser{lang: "toml", syntax: tomlSyntax}
+}
+
+// NewMakefileParser creates a new Makefile parser
+func NewMakefileParser() *HashParser {
+	return &HashParser{lang: "make", syntax: makeSyntax}
+}
+
+// Tokenize splits content into lexical tokens
+func (p *HashParser) Tokenize(content string) []Token {
+	return tokenizeHash(content, p.syntax)
+}
+
+// Parse extracts all comments from the content
+func (p *HashParser) Parse(content string) ParseResult {
+	var comments []Comment
+
+	tokens := p.Tokenize(content)
+	for _, tok := range tokens {
+		if tok.Kind != TokenComment {
+			continue
+		}
+		original := tok.Text(content)
+		isBlock := original[0] == '='
+
+		var text string
+		kind := KindStandalone
+		switch {
+		case isBlock:
+			_, text, _ = splitHashBlock(original)
+			if !strings.HasPrefix(original, "=begin") {
+				// Perl POD is documentation
+				kind = KindDoc
+			}
+		case p.lang == "r" && strings.HasPrefix(original, "#'"):
+			// Roxygen documentation
+			text = original[2:]
+			kind = KindDoc
+		default:
+			text = strings.TrimLeft(original, "#")
+		}
+
+		comments = append(comments, Comment{
+			Text:       strings.TrimSpace(text),
+			Start:      tok.Start,
+			End:        tok.End,
+			LineNumber: countLines(content[:tok.Start]) + 1,
+			IsBlock:    isBlock,
+			Original:   original,
+			Kind:       kind,
+		})
+	}
+
+	classifyComments(content, comments, firstCodeStart(content, tokens), Directives(p.lang))
+
+	return ParseResult{
+		Content:  content,
+		Comments: comments,
+	}
+}
+
+// ReplaceComment replaces a comment in the content with new text. Line
+// comments keep their marker (##, #'); blocks keep their opening and
+// closing lines.
+func (p *HashParser) ReplaceComment(content string, comment Comment, newText string) string {
+	if !isIntact(content, comment) {
+		return content
+	}
+	if newText == "" {
+		return removeComment(content, comment)
+	}
+
+	var replacement string
+	if comment.IsBlock {
+		open, body, close := splitHashBlock(comment.Original)
+		sep := "\n"
+		if strings.HasPrefix(body, "\n") || strings.HasPrefix(body, "\r\n") {
+			// POD paragraphs are separated by blank lines
+			sep = "\n\n"
+		}
+		replacement = open + sep + newText
+		if close != "" {
+			replacement += sep + close
+		}
+	} else {
+		opener := comment.Original[:len(comment.Original)-len(strings.TrimLeft(comment.Original, "#"))]
+		if comment.Kind == KindDoc && strings.HasPrefix(comment.Original, "#'") {
+			opener = "#'"
+		}
+		replacement = opener + " " + newText
+	}
+	return content[:comment.Start] + replacement + content[comment.End:]
+}
+
+// splitHashBlock splits a =begin/=end or POD block into its opening
+// line, body and closing line. close is empty for unterminated blocks.
+func splitHashBlock(original string) (open, body, close string) {
+	lines := strings.Split(original, "\n")
+	open = strings.TrimSuffix(lines[0], "\r")
+	lines = lines[1:]
+	if n := len(lines); n > 0 && (isMarkerLine(lines[n-1], "=end") || isMarkerLine(lines[n-1], "=cut")) {
+		close = lines[n-1]
+		lines = lines[:n-1]
+	}
+	return open, strings.Join(lines, "\n"), close
+}
@@ -0,0 +1,585 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// hashSyntax configures the lexer for languages whose line comments start
+// with '#'. Each flag switches on one language's string or block rules.
+type hashSyntax struct {
+	wordComments    bool   // '#' only starts a comment at the start of a word (shells)
+	quotes          string // Characters that open quoted strings
+	rawSingle       bool   // '...' has no escapes (shells)
+	tripleQuotes    bool   // """...""" and '''...''' strings (TOML)
+	heredocs        bool   // <<EOF ... EOF bodies (shells, Ruby, Perl)
+	interpolation   string // Opener of code nested in "..." strings: "#{" (Ruby) or "$(" (shells)
+	specialVars     bool   // $' $" $# and friends are variables, not quotes or comments (Ruby, Perl)
+	blockComments   bool   // =begin ... =end (Ruby) and =pod ... =cut (Perl) at line start
+	endMarker       bool   // __END__ / __DATA__ ends the code (Ruby, Perl)
+	regexLiterals   bool   // /.../ in expression position (Ruby, Perl)
+	percentLiterals bool   // %q(...), %w[...] and friends (Ruby)
+	quoteLike       bool   // q(), qq{}, qw//, m//, s///, tr/// (Perl)
+	rawStrings      bool   // r"(...)" raw strings (R)
+	yaml            bool   // Quotes only open scalars; | and > block scalars
+	makefile        bool   // \# escapes, $(...) references, define blocks, shell recipes
+}
+
+// hashRegexKeywords are keywords after which '/' starts a regex (Ruby, Perl)
+var hashRegexKeywords = map[string]bool{
+	"if": true, "unless": true, "while": true, "until": true, "when": true,
+	"and": true, "or": true, "not": true, "return": true, "split": true,
+	"grep": true, "map": true, "elsif": true,
+}
+
+// perlQuoteOps maps Perl quote-like operators to their number of parts
+var perlQuoteOps = map[string]int{
+	"q": 1, "qq": 1, "qw": 1, "qx": 1, "qr": 1, "m": 1,
+	"s": 2, "tr": 2, "y": 2,
+}
+
+var (
+	shellHeredocRe = regexp.MustCompile(`^<<(-?)[ \t]*(?:'([^'\n]+)'|"([^"\n]+)"|\\?([A-Za-z_][A-Za-z0-9_]*))`)
+	rubyHeredocRe  = regexp.MustCompile(`^<<([~-]?)(?:'([^'\n]+)'|"([^"\n]+)"|([A-Z_][A-Za-z0-9_]*))`)
+	perlHeredocRe  = regexp.MustCompile(`^<<(~?)(?:'([^'\n]+)'|"([^"\n]+)"|([A-Z_][A-Za-z0-9_]*))`)
+	rubyPercentRe  = regexp.MustCompile(`^%[qQwWiIrsx]?[^A-Za-z0-9\s]`)
+	yamlBlockRe    = regexp.MustCompile(`^[|>][0-9+-]*[ \t]*(#.*)?$`)
+)
+
+// heredoc is a pending heredoc whose body starts on the next line
+type heredoc struct {
+	delim    string
+	indented bool // The terminator may be indented (<<-, <<~)
+}
+
+// hashLexer splits hash-comment source into tokens
+type hashLexer struct {
+	syntax   hashSyntax
+	src      string
+	pos      int
+	tokens   []Token
+	pending  []heredoc
+	depth    int // Nesting of $( ... ) references (Make)
+	arith    int // Open parentheses of a $(( )) or (( )) arithmetic expression (shells)
+	recipe   bool
+	blockInd int // Indentation of the line that opened a YAML block scalar, or -1
+}
+
+// tokenizeHash returns the tokens in hash-comment content
+func tokenizeHash(content string, syntax hashSyntax) []Token {
+	l := &hashLexer{syntax: syntax, src: content, blockInd: -1}
+	l.lex()
+	return l.tokens
+}
+
+func (l *hashLexer) emit(kind TokenKind, start int) {
+	if l.pos > start {
+		l.tokens = append(l.tokens, Token{Kind: kind, Start: start, End: l.pos})
+	}
+}
+
+// peek returns the byte n positions ahead, or 0 past the end
+func (l *hashLexer) peek(n int) byte {
+	if l.pos+n < len(l.src) {
+		return l.src[l.pos+n]
+	}
+	return 0
+}
+
+func (l *hashLexer) lex() {
+	for l.pos < len(l.src) {
+		if l.pos == 0 || l.src[l.pos-1] == '\n' {
+			if l.lexLineStart() {
+				continue
+			}
+		}
+
+		c := l.src[l.pos]
+		start := l.pos
+
+		switch {
+		case c == '\n':
+			l.pos++
+			if !strings.HasSuffix(strings.TrimRight(l.src[:l.pos-1], "\r"), "\\") {
+				l.depth = 0
+			}
+
+		case isSpaceByte(c):
+			l.pos++
+
+		case c == '#' && l.isCommentStart():
+			l.pos = lineEnd(l.src, l.pos)
+			l.emit(TokenComment, start)
+
+		case c == '\\' && (l.shellRules() || l.syntax.makefile):
+			// Escaped character such as \# or \'
+			l.pos += 2
+			if l.pos > len(l.src) {
+				l.pos = len(l.src)
+			}
+			l.emit(TokenCode, start)
+
+		case c == '$' && l.syntax.specialVars && l.peek(1) != 0 && strings.IndexByte("'\"#`;,./\\!@~&*?<>:$0", l.peek(1)) >= 0:
+			l.pos += 2
+			l.emit(TokenCode, start)
+
+		case c == '$' && l.shellRules() && (l.peek(1) == '\'' || l.peek(1) == '"'):
+			// $'...' ANSI-C and $"..." locale strings
+			l.pos++
+			l.lexQuoted(l.src[l.pos], true)
+			l.emit(TokenString, start)
+
+		case c == '$' && l.syntax.makefile && !l.recipe && (l.peek(1) == '(' || l.peek(1) == '{'):
+			l.depth++
+			l.pos += 2
+			l.emit(TokenCode, start)
+
+		case (c == ')' || c == '}') && l.syntax.makefile && l.depth > 0:
+			l.depth--
+			l.pos++
+			l.emit(TokenCode, start)
+
+		case c == '(' && l.syntax.wordComments && (l.arith > 0 || l.peek(1) == '('):
+			// $(( ... )) and (( ... )) arithmetic, where << is a shift
+			l.arith++
+			l.pos++
+			l.emit(TokenCode, start)
+
+		case c == ')' && l.arith > 0:
+			l.arith--
+			l.pos++
+			l.emit(TokenCode, start)
+
+		case c == '<' && l.peek(1) == '<' && l.syntax.heredocs && l.arith == 0 && l.lexHeredocMarker():
+			l.emit(TokenCode, start)
+
+		case l.syntax.tripleQuotes && (strings.HasPrefix(l.src[l.pos:], `"""`) || strings.HasPrefix(l.src[l.pos:], `'''`)):
+			l.pos = blockEnd(l.src, l.pos+3, l.src[l.pos:l.pos+3])
+			l.emit(TokenString, start)
+
+		case strings.IndexByte(l.quotes(), c) >= 0 && l.quoteAllowed():
+			l.lexQuoted(c, !l.syntax.tripleQuotes)
+			l.emit(TokenString, start)
+
+		case c == '/' && l.syntax.regexLiterals && l.regexAllowed() && l.lexRegex():
+			l.emit(TokenString, start)
+
+		case c == '%' && l.syntax.percentLiterals && l.lexPercentLiteral():
+			l.emit(TokenString, start)
+
+		case c == '|' || c == '>':
+			if l.syntax.yaml && l.quoteAllowed() && yamlBlockRe.MatchString(l.src[l.pos:lineEnd(l.src, l.pos)]) {
+				l.blockInd = lineIndent(l.src, l.pos)
+			}
+			l.pos++
+			l.emit(TokenCode, start)
+
+		case isIdentByte(c):
+			for l.pos < len(l.src) && isIdentByte(l.src[l.pos]) {
+				l.pos++
+			}
+			if !l.lexPrefixedString(start) {
+				l.emit(TokenCode, start)
+			} else {
+				l.emit(TokenString, start)
+			}
+
+		default:
+			l.pos++
+			l.emit(TokenCode, start)
+		}
+	}
+}
+
+// shellRules reports whether shell quoting and comment rules apply at the
+// current position: always for shells, and in Makefile recipe lines
+func (l *hashLexer) shellRules() bool {
+	return l.syntax.wordComments || l.recipe
+}
+
+// quotes returns the characters that open strings at the current position
+func (l *hashLexer) quotes() string {
+	if l.recipe {
+		return "'\"`"
+	}
+	return l.syntax.quotes
+}
+
+// lexLineStart handles constructs recognized only at the start of a line:
+// heredoc bodies, block comments, end markers, YAML block scalars and
+// Makefile define blocks. It reports whether it consumed anything.
+func (l *hashLexer) lexLineStart() bool {
+	start := l.pos
+	line := l.src[l.pos:lineEnd(l.src, l.pos)]
+	l.recipe = l.syntax.makefile && strings.HasPrefix(line, "\t")
+
+	if len(l.pending) > 0 {
+		for _, h := range l.pending {
+			l.skipHeredocBody(h)
+		}
+		l.pending = nil
+		l.emit(TokenString, start)
+		return true
+	}
+
+	if l.syntax.yaml && l.blockInd >= 0 {
+		if strings.TrimSpace(line) == "" || lineIndent(l.src, l.pos) > l.blockInd {
+			l.pos = l.nextLine(l.pos)
+			l.emit(TokenString, start)
+			return true
+		}
+		l.blockInd = -1
+	}
+
+	if l.syntax.blockComments && len(line) > 1 && line[0] == '=' && isIdentByte(line[1]) {
+		// Ruby =begin ... =end or Perl POD up to =cut
+		closer := "=cut"
+		if strings.HasPrefix(line, "=begin") {
+			closer = "=end"
+		}
+		l.pos = l.nextLine(l.pos)
+		for l.pos < len(l.src) && !isMarkerLine(l.src[l.pos:lineEnd(l.src, l.pos)], closer) {
+			l.pos = l.nextLine(l.pos)
+		}
+		l.pos = lineEnd(l.src, l.pos)
+		l.emit(TokenComment, start)
+		return true
+	}
+
+	if l.syntax.endMarker && (isMarkerLine(line, "__END__") || isMarkerLine(line, "__DATA__")) {
+		l.pos = len(l.src)
+		l.emit(TokenString, start)
+		return true
+	}
+
+	if l.syntax.makefile && isMarkerLine(strings.TrimSpace(line), "define") {
+		for l.pos < len(l.src) && !isMarkerLine(strings.TrimSpace(l.src[l.pos:lineEnd(l.src, l.pos)]), "endef") {
+			l.pos = l.nextLine(l.pos)
+		}
+		l.pos = lineEnd(l.src, l.pos)
+		l.emit(TokenString, start)
+		return true
+	}
+
+	return false
+}
+
+// nextLine returns the position after the newline ending the line at pos
+func (l *hashLexer) nextLine(pos int) int {
+	if i := strings.IndexByte(l.src[pos:], '\n'); i >= 0 {
+		return pos + i + 1
+	}
+	return len(l.src)
+}
+
+// isMarkerLine reports whether line is marker, optionally followed by
+// whitespace and more text (=end, =cut, __END__, define NAME)
+func isMarkerLine(line, marker string) bool {
+	if !strings.HasPrefix(line, marker) {
+		return false
+	}
+	rest := line[len(marker):]
+	return rest == "" || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r'
+}
+
+// isCommentStart reports whether the '#' at the current position starts a comment
+func (l *hashLexer) isCommentStart() bool {
+	prev := byte('\n')
+	if l.pos > 0 {
+		prev = l.src[l.pos-1]
+	}
+
+	switch {
+	case l.shellRules():
+		// Only at the start of a word: not $#, ${#x} or a#b
+		return strings.IndexByte(" \t\r\n;&|()<>", prev) >= 0
+	case l.syntax.yaml:
+		return isSpaceByte(prev)
+	case l.syntax.makefile:
+		return l.depth == 0
+	case l.syntax.percentLiterals:
+		// Ruby ?# character literal
+		return prev != '?'
+	}
+	return true
+}
+
+// quoteAllowed reports whether a quote at the current position opens a
+// string. YAML quotes only open a scalar at its start.
+func (l *hashLexer) quoteAllowed() bool {
+	if !l.syntax.yaml {
+		return true
+	}
+	before := strings.TrimRight(l.src[lineStartOf(l.src, l.pos):l.pos], " \t")
+	if before == "" {
+		return true
+	}
+	return strings.IndexByte(":-[{,?", before[len(before)-1]) >= 0
+}
+
+// lexQuoted scans a quoted string. Shell single quotes have no escapes;
+// other strings honor backslashes and skip nested interpolation.
+func (l *hashLexer) lexQuoted(quote byte, multiline bool) {
+	raw := quote == '\'' && l.shellRules() && l.syntax.rawSingle && (l.pos == 0 || l.src[l.pos-1] != '$')
+	l.pos++
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\\' && !raw:
+			l.pos += 2
+			continue
+		case c == quote && l.syntax.yaml && quote == '\'' && l.peek(1) == '\'':
+			// YAML '' escape
+			l.pos += 2
+			continue
+		case c == quote:
+			l.pos++
+			return
+		case c == '\n' && !multiline:
+			return
+		case quote != '\'' && l.syntax.interpolation != "" && strings.HasPrefix(l.src[l.pos:], l.syntax.interpolation):
+			l.skipNested(l.syntax.interpolation[len(l.syntax.interpolation)-1])
+			continue
+		case quote == '"' && l.shellRules() && strings.HasPrefix(l.src[l.pos:], "${"):
+			l.skipNested('{')
+			continue
+		}
+		l.pos++
+	}
+	if l.pos > len(l.src) {
+		l.pos = len(l.src)
+	}
+}
+
+// skipNested skips an interpolation such as $(...), ${...} or #{...},
+// including any strings nested inside it
+func (l *hashLexer) skipNested(open byte) {
+	close := byte(')')
+	if open == '{' {
+		close = '}'
+	}
+	l.pos = strings.IndexByte(l.src[l.pos:], open) + l.pos + 1
+	depth := 0
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\\':
+			l.pos += 2
+			continue
+		case c == '"' || c == '\'' || c == '`':
+			l.lexQuoted(c, true)
+			continue
+		case c == open:
+			depth++
+		case c == close:
+			if depth == 0 {
+				l.pos++
+				return
+			}
+			depth--
+		}
+		l.pos++
+	}
+}
+
+// lexHeredocMarker scans a heredoc marker such as <<-EOF or <<~'SQL' and
+// queues its body, which starts on the next line. The <<< of a
+// here-string is scanned as a whole so its word is not taken as a marker.
+func (l *hashLexer) lexHeredocMarker() bool {
+	re := shellHeredocRe
+	switch {
+	case l.syntax.percentLiterals:
+		re = rubyHeredocRe
+	case l.syntax.quoteLike:
+		re = perlHeredocRe
+	}
+	if strings.HasPrefix(l.src[l.pos:], "<<<") {
+		// Here-string, not a heredoc
+		l.pos += 3
+		return true
+	}
+	m := re.FindStringSubmatch(l.src[l.pos:])
+	if m == nil {
+		return false
+	}
+	delim := m[2] + m[3] + m[4]
+	l.pending = append(l.pending, heredoc{delim: delim, indented: m[1] != ""})
+	l.pos += len(m[0])
+	return true
+}
+
+// skipHeredocBody skips lines up to and including the heredoc terminator
+func (l *hashLexer) skipHeredocBody(h heredoc) {
+	for l.pos < len(l.src) {
+		end := lineEnd(l.src, l.pos)
+		line := l.src[l.pos:end]
+		if h.indented {
+			line = strings.TrimLeft(line, " \t")
+		}
+		l.pos = l.nextLine(l.pos)
+		if line == h.delim {
+			return
+		}
+	}
+}
+
+// regexAllowed reports whether the previous token puts us in expression
+// position, where '/' starts a regex rather than a division
+func (l *hashLexer) regexAllowed() bool {
+	for i := len(l.tokens) - 1; i >= 0; i-- {
+		t := l.tokens[i]
+		if t.Kind == TokenComment {
+			continue
+		}
+		if t.Kind == TokenString {
+			return false
+		}
+		text := t.Text(l.src)
+		if isIdentByte(text[0]) {
+			return hashRegexKeywords[text]
+		}
+		return text != ")" && text != "]" && text != "}"
+	}
+	return true
+}
+
+// lexRegex scans a /.../ literal closed on the same line
+func (l *hashLexer) lexRegex() bool {
+	end, ok := l.delimited(l.pos, '/', false)
+	if !ok || strings.Contains(l.src[l.pos:end], "\n") {
+		return false
+	}
+	l.pos = end
+	l.skipModifiers()
+	return true
+}
+
+// lexPercentLiteral scans a Ruby %q(...) style literal
+func (l *hashLexer) lexPercentLiteral() bool {
+	m := rubyPercentRe.FindString(l.src[l.pos:])
+	if m == "" {
+		return false
+	}
+	// A bare %( is only a literal where an expression can start
+	if len(m) == 2 && (strings.IndexByte("([{<|!", m[1]) < 0 || !l.regexAllowed()) {
+		return false
+	}
+	end, ok := l.delimited(l.pos+len(m)-1, m[len(m)-1], true)
+	if !ok {
+		return false
+	}
+	l.pos = end
+	l.skipModifiers()
+	return true
+}
+
+// lexPrefixedString checks whether the word just scanned from start opens
+// a string: a Perl quote-like operator or an R raw string. It reports
+// false when the word is ordinary code.
+func (l *hashLexer) lexPrefixedString(start int) bool {
+	word := l.src[start:l.pos]
+
+	if l.syntax.rawStrings && (word == "r" || word == "R") && (l.peek(0) == '"' || l.peek(0) == '\'') {
+		quote := l.src[l.pos]
+		pos := l.pos + 1
+		dashes := 0
+		for pos < len(l.src) && l.src[pos] == '-' {
+			dashes++
+			pos++
+		}
+		if pos >= len(l.src) || strings.IndexByte("([{", l.src[pos]) < 0 {
+			return false
+		}
+		closer := string(matchingBracket(l.src[pos])) + strings.Repeat("-", dashes) + string(quote)
+		l.pos = blockEnd(l.src, pos+1, closer)
+		return true
+	}
+
+	parts, ok := perlQuoteOps[word]
+	if !l.syntax.quoteLike || !ok {
+		return false
+	}
+	// $s, @q, %y and ->m are variables and methods, not operators
+	if start > 0 && strings.IndexByte("$@%&>", l.src[start-1]) >= 0 {
+		return false
+	}
+	pos := l.pos
+	for pos < len(l.src) && (l.src[pos] == ' ' || l.src[pos] == '\t') {
+		pos++
+	}
+	if pos >= len(l.src) {
+		return false
+	}
+	delim := l.src[pos]
+	if isIdentByte(delim) || isSpaceByte(delim) || strings.IndexByte("=,;)]}>", delim) >= 0 || (delim == '#' && pos > l.pos) {
+		return false
+	}
+
+	end, ok := l.delimited(pos, delim, true)
+	if !ok {
+		return false
+	}
+	if parts == 2 {
+		if matchingBracket(delim) != delim {
+			// s{...}{...}: the replacement has its own delimiters
+			for end < len(l.src) && isSpaceByte(l.src[end]) {
+				end++
+			}
+			if end >= len(l.src) {
+				return false
+			}
+			end, ok = l.delimited(end, l.src[end], true)
+		} else {
+			end, ok = l.delimited(end-1, delim, true)
+		}
+		if !ok {
+			return false
+		}
+	}
+	l.pos = end
+	l.skipModifiers()
+	return true
+}
+
+// delimited returns the position after the literal whose opening
+// delimiter is at pos. Bracket delimiters nest when nest is true.
+func (l *hashLexer) delimited(pos int, open byte, nest bool) (int, bool) {
+	close := matchingBracket(open)
+	depth := 0
+	for i := pos + 1; i < len(l.src); i++ {
+		c := l.src[i]
+		switch {
+		case c == '\\':
+			i++
+		case c == close && depth == 0:
+			return i + 1, true
+		case c == close:
+			depth--
+		case c == open && open != close && nest:
+			depth++
+		}
+	}
+	return 0, false
+}
+
+// skipModifiers skips regex flags such as /i or /gx
+func (l *hashLexer) skipModifiers() {
+	for l.pos < len(l.src) && isIdentByte(l.src[l.pos]) && !isDigitByte(l.src[l.pos]) {
+		l.pos++
+	}
+}
+
+// matchingBracket returns the closing bracket for open, or open itself
+func matchingBracket(open byte) byte {
+	switch open {
+	case '(':
+		return ')'
+	case '[':
+		return ']'
+	case '{':
+		return '}'
+	case '<':
+		return '>'
+	}
+	return open
+}
@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"regexp"
 	"strings"
 )
 
@@ -13,53 +12,52 @@ func NewJavaScriptParser() *JavaScriptParser {
 	return &JavaScriptParser{}
 }
 
-var (
-	// Match single-line comments: // ...
-	jsLineCommentRe = regexp.MustCompile(`(?m)//[^\n]*`)
-	// Match block comments: /* ... */
-	jsBlockCommentRe = regexp.MustCompile(`(?s)/\*.*?\*/`)
-)
+// Tokenize splits JavaScript/TypeScript content into lexical tokens
+func (p *JavaScriptParser) Tokenize(content string) []Token {
+	return tokenizeJS(content)
+}
 
 // Parse extracts all comments from JavaScript/TypeScript content
 func (p *JavaScriptParser) Parse(content string) ParseResult {
 	var comments []Comment
 
-	// Find line comments
-	for _, match := range jsLineCommentRe.FindAllStringIndex(content, -1) {
-		start, end := match[0], match[1]
-		original := content[start:end]
-		text := strings.TrimPrefix(original, "//")
+	tokens := p.Tokenize(content)
+	for _, tok := range tokens {
+		if tok.Kind != TokenComment {
+			continue
+		}
+		original := tok.Text(content)
+		isBlock := strings.HasPrefix(original, "/*")
+		kind := KindStandalone
+
+		var text string
+		switch {
+		case isBlock:
+			text = strings.TrimPrefix(original, "/*")
+			text = strings.TrimSuffix(text, "*/")
+			if strings.HasPrefix(text, "*") && text != "*" {
+				kind = KindJSDoc
+				text = strings.TrimPrefix(text, "*")
+			}
+		case strings.HasPrefix(original, "#!"):
+			text = strings.TrimPrefix(original, "#!")
+		default:
+			text = strings.TrimPrefix(original, "//")
+		}
 		text = strings.TrimSpace(text)
-		lineNum := countLines(content[:start]) + 1
 
 		comments = append(comments, Comment{
 			Text:       text,
-			Start:      start,
-			End:        end,
-			LineNumber: lineNum,
-			IsBlock:    false,
+			Start:      tok.Start,
+			End:        tok.End,
+			LineNumber: countLines(content[:tok.Start]) + 1,
+			IsBlock:    isBlock,
 			Original:   original,
+			Kind:       kind,
 		})
 	}
 
-	// Find block comments
-	for _, match := range jsBlockCommentRe.FindAllStringIndex(content, -1) {
-		start, end := match[0], match[1]
-		original := content[start:end]
-		text := strings.TrimPrefix(original, "/*")
-		text = strings.TrimSuffix(text, "*/")
-		text = strings.TrimSpace(text)
-		lineNum := countLines(content[:start]) + 1
-
-		comments = append(comments, Comment{
-			Text:       text,
-			Start:      start,
-			End:        end,
-			LineNumber: lineNum,
-			IsBlock:    true,
-			Original:   original,
-		})
-	}
+	classifyComments(content, comments, firstCodeStart(content, tokens), Directives("js"))
 
 	return ParseResult{
 		Content:  content,
@@ -69,37 +67,7 @@ func (p *JavaScriptParser) Parse(content string) ParseResult {
 
 // ReplaceComment replaces a comment in the content with new text
 func (p *JavaScriptParser) ReplaceComment(content string, comment Comment, newText string) string {
-	var replacement string
-	if newText == "" {
-		// Remove the comment entirely
-		startIdx := comment.Start
-		endIdx := comment.End
-
-		// If comment is at start of line (only whitespace before it), remove the whole line
-		lineStart := startIdx
-		for lineStart > 0 && content[lineStart-1] != '\n' {
-			lineStart--
-		}
-		prefix := content[lineStart:startIdx]
-		if strings.TrimSpace(prefix) == "" {
-			// Only whitespace before comment, remove from line start
-			startIdx = lineStart
-		}
-
-		// Remove trailing newline if present
-		if endIdx < len(content) && content[endIdx] == '\n' {
-			endIdx++
-		}
-
-		return content[:startIdx] + content[endIdx:]
-	}
-
-	if comment.IsBlock {
-		replacement = "/* " + newText + " */"
-	} else {
-		replacement = "// " + newText
-	}
-	return content[:comment.Start] + replacement + content[comment.End:]
+	return replaceCStyleComment(content, comment, newText)
 }
 
 func countLines(s string) int {
@@ -0,0 +1,423 @@
+package parser
+
+import "strings"
+
+// jsOperators lists multi-character JS/TS operators, longest first
+var jsOperators = []string{
+	">>>=", "...", "===", "!==", "**=", "<<=", ">>=", ">>>", "&&=", "||=", "??=",
+	"=>", "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "++", "--",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "**", "<<", ">>",
+}
+
+// jsRegexKeywords are keywords after which a '/' starts a regex literal
+var jsRegexKeywords = map[string]bool{
+	"return":     true,
+	"typeof":     true,
+	"instanceof": true,
+	"in":         true,
+	"of":         true,
+	"new":        true,
+	"delete":     true,
+	"void":       true,
+	"throw":      true,
+	"case":       true,
+	"do":         true,
+	"else":       true,
+	"yield":      true,
+	"await":      true,
+}
+
+// jsLexer splits JavaScript/TypeScript source into tokens. It tracks
+// strings, template literals with nested ${} expressions, regex literals
+// and JSX text so comment markers inside them are never reported.
+type jsLexer struct {
+	src    string
+	pos    int
+	tokens []Token
+}
+
+// tokenizeJS returns the tokens in JavaScript/TypeScript content
+func tokenizeJS(content string) []Token {
+	l := &jsLexer{src: content}
+	if strings.HasPrefix(content, "#!") {
+		l.pos = lineEnd(content, 0)
+		l.emit(TokenComment, 0)
+	}
+	l.lexCode(false)
+	return l.tokens
+}
+
+func (l *jsLexer) emit(kind TokenKind, start int) {
+	l.tokens = append(l.tokens, Token{Kind: kind, Start: start, End: l.pos})
+}
+
+// lexCode scans code until EOF. When nested is true it stops before the
+// unmatched '}' that closes a template or JSX expression.
+func (l *jsLexer) lexCode(nested bool) {
+	depth := 0
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		start := l.pos
+
+		switch {
+		case isSpaceByte(c):
+			l.pos++
+
+		case c == '/' && l.peek(1) == '/':
+			l.pos = lineEnd(l.src, l.pos)
+			l.emit(TokenComment, start)
+
+		case c == '/' && l.peek(1) == '*':
+			l.pos = blockEnd(l.src, l.pos+2, "*/")
+			l.emit(TokenComment, start)
+
+		case c == '/' && l.regexAllowed() && l.lexRegex():
+			l.emit(TokenString, start)
+
+		case c == '\'' || c == '"':
+			l.lexQuoted(c)
+			l.emit(TokenString, start)
+
+		case c == '`':
+			l.lexTemplate()
+
+		case c == '<' && l.regexAllowed() && l.jsxStart() && l.lexJSX():
+			// tokens emitted by lexJSX
+
+		case isIdentByte(c) && !isDigitByte(c), c == '\\':
+			for l.pos < len(l.src) && (isIdentByte(l.src[l.pos]) || l.src[l.pos] == '\\') {
+				l.pos++
+			}
+			l.emit(TokenCode, start)
+
+		case isDigitByte(c), c == '.' && isDigitByte(l.peek(1)):
+			l.lexNumber()
+			l.emit(TokenCode, start)
+
+		case c == '{':
+			depth++
+			l.pos++
+			l.emit(TokenCode, start)
+
+		case c == '}':
+			if nested && depth == 0 {
+				return
+			}
+			depth--
+			l.pos++
+			l.emit(TokenCode, start)
+
+		default:
+			l.pos += len(matchOperator(l.src, l.pos, jsOperators))
+			l.emit(TokenCode, start)
+		}
+	}
+}
+
+// peek returns the byte n positions ahead, or 0 past the end
+func (l *jsLexer) peek(n int) byte {
+	if l.pos+n < len(l.src) {
+		return l.src[l.pos+n]
+	}
+	return 0
+}
+
+// regexAllowed reports whether the previous token puts us in expression
+// position, where '/' starts a regex and '<' may start JSX
+func (l *jsLexer) regexAllowed() bool {
+	for i := len(l.tokens) - 1; i >= 0; i-- {
+		t := l.tokens[i]
+		if t.Kind == TokenComment {
+			continue
+		}
+		if t.Kind == TokenString {
+			return false
+		}
+		text := t.Text(l.src)
+		last := text[len(text)-1]
+		switch {
+		case isDigitByte(text[0]):
+			return false
+		case isIdentByte(last) || last == '\\':
+			return jsRegexKeywords[text]
+		case text == ")" || text == "]" || text == "}" || text == "++" || text == "--":
+			return false
+		}
+		return true
+	}
+	return true
+}
+
+// lexQuoted scans a '...' or "..." string. Strings end at an unescaped
+// newline so an unterminated quote cannot swallow the rest of the file.
+func (l *jsLexer) lexQuoted(quote byte) {
+	l.pos++
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch c {
+		case '\\':
+			l.pos += 2
+			continue
+		case quote:
+			l.pos++
+			return
+		case '\n':
+			return
+		}
+		l.pos++
+	}
+	l.pos = len(l.src)
+}
+
+// lexTemplate scans a template literal, lexing ${} expressions as code
+func (l *jsLexer) lexTemplate() {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\\':
+			l.pos += 2
+		case c == '`':
+			l.pos++
+			l.emit(TokenString, start)
+			return
+		case c == '$' && l.peek(1) == '{':
+			l.pos += 2
+			l.emit(TokenString, start)
+			l.lexCode(true)
+			start = l.pos
+			if l.pos < len(l.src) {
+				l.pos++ // closing '}' belongs to the next template chunk
+			}
+		default:
+			l.pos++
+		}
+	}
+	l.pos = len(l.src)
+	l.emit(TokenString, start)
+}
+
+// lexRegex scans a regex literal. It reports false, leaving the position
+// untouched, when the literal is not closed on the same line.
+func (l *jsLexer) lexRegex() bool {
+	pos := l.pos + 1
+	inClass := false
+	for pos < len(l.src) {
+		c := l.src[pos]
+		switch {
+		case c == '\n' || c == '\r':
+			return false
+		case c == '\\':
+			pos++
+			if pos < len(l.src) && l.src[pos] == '\n' {
+				return false
+			}
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '/' && !inClass:
+			pos++
+			for pos < len(l.src) && isIdentByte(l.src[pos]) {
+				pos++
+			}
+			l.pos = pos
+			return true
+		}
+		pos++
+	}
+	return false
+}
+
+// lexNumber scans a numeric literal including exponents and separators
+func (l *jsLexer) lexNumber() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if isIdentByte(c) || c == '.' {
+			l.pos++
+			continue
+		}
+		if (c == '+' || c == '-') && (l.src[l.pos-1] == 'e' || l.src[l.pos-1] == 'E') &&
+			!strings.HasPrefix(strings.ToLower(l.src[:l.pos]), "0x") {
+			l.pos++
+			continue
+		}
+		break
+	}
+}
+
+// jsxStart reports whether the '<' at the current position looks like the
+// start of a JSX element or fragment
+func (l *jsLexer) jsxStart() bool {
+	c := l.peek(1)
+	return c == '>' || (isIdentByte(c) && !isDigitByte(c))
+}
+
+// lexJSX scans a JSX element starting at '<'. If the element cannot be
+// closed (for example a TypeScript type assertion), it restores the lexer
+// state and reports false so the '<' is treated as an operator.
+func (l *jsLexer) lexJSX() bool {
+	savedPos, savedLen := l.pos, len(l.tokens)
+	if l.lexJSXElement() {
+		return true
+	}
+	l.pos, l.tokens = savedPos, l.tokens[:savedLen]
+	return false
+}
+
+func (l *jsLexer) lexJSXElement() bool {
+	start := l.pos
+	l.pos++ // '<'
+	l.lexJSXName()
+	l.emit(TokenCode, start)
+
+	// Attributes
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.src) {
+			return false
+		}
+		c := l.src[l.pos]
+		start = l.pos
+		switch {
+		case c == '/' && l.peek(1) == '>':
+			l.pos += 2
+			l.emit(TokenCode, start)
+			return true
+		case c == '>':
+			l.pos++
+			l.emit(TokenCode, start)
+			return l.lexJSXChildren()
+		case c == '{':
+			if !l.lexJSXExpression() {
+				return false
+			}
+		case isIdentByte(c) && !isDigitByte(c):
+			l.lexJSXName()
+			l.emit(TokenCode, start)
+			l.skipSpace()
+			if l.peek(0) != '=' {
+				continue
+			}
+			start = l.pos
+			l.pos++
+			l.emit(TokenCode, start)
+			l.skipSpace()
+			if !l.lexJSXAttrValue() {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+}
+
+// lexJSXAttrValue scans a quoted, braced or element attribute value
+func (l *jsLexer) lexJSXAttrValue() bool {
+	start := l.pos
+	switch l.peek(0) {
+	case '"', '\'':
+		// JSX attribute strings may span lines and have no escapes
+		end := strings.IndexByte(l.src[l.pos+1:], l.src[l.pos])
+		if end < 0 {
+			return false
+		}
+		l.pos += end + 2
+		l.emit(TokenString, start)
+		return true
+	case '{':
+		return l.lexJSXExpression()
+	case '<':
+		return l.lexJSXElement()
+	}
+	return false
+}
+
+// lexJSXChildren scans element children up to and including the closing tag
+func (l *jsLexer) lexJSXChildren() bool {
+	for l.pos < len(l.src) {
+		start := l.pos
+		switch l.src[l.pos] {
+		case '<':
+			if l.peek(1) != '/' {
+				if !l.lexJSXElement() {
+					return false
+				}
+				continue
+			}
+			end := strings.IndexByte(l.src[l.pos:], '>')
+			if end < 0 {
+				return false
+			}
+			l.pos += end + 1
+			l.emit(TokenCode, start)
+			return true
+		case '{':
+			if !l.lexJSXExpression() {
+				return false
+			}
+		default:
+			for l.pos < len(l.src) && l.src[l.pos] != '<' && l.src[l.pos] != '{' {
+				l.pos++
+			}
+			if strings.TrimSpace(l.src[start:l.pos]) != "" {
+				l.emit(TokenString, start)
+			}
+		}
+	}
+	return false
+}
+
+// lexJSXExpression scans a {...} expression inside JSX as ordinary code
+func (l *jsLexer) lexJSXExpression() bool {
+	start := l.pos
+	l.pos++
+	l.emit(TokenCode, start)
+	l.lexCode(true)
+	if l.pos >= len(l.src) {
+		return false
+	}
+	start = l.pos
+	l.pos++
+	l.emit(TokenCode, start)
+	return true
+}
+
+// lexJSXName scans a tag or attribute name such as a.b, svg:rect or data-x
+func (l *jsLexer) lexJSXName() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if !isIdentByte(c) && c != '.' && c != ':' && c != '-' {
+			return
+		}
+		l.pos++
+	}
+}
+
+func (l *jsLexer) skipSpace() {
+	for l.pos < len(l.src) && isSpaceByte(l.src[l.pos]) {
+		l.pos++
+	}
+}
+
+// lineEnd returns the position of the newline ending the line at pos
+func lineEnd(src string, pos int) int {
+	if i := strings.IndexByte(src[pos:], '\n'); i >= 0 {
+		end := pos + i
+		if end > pos && src[end-1] == '\r' {
+			end--
+		}
+		return end
+	}
+	return len(src)
+}
+
+// blockEnd returns the position just past closer, searching from pos.
+// Unterminated blocks run to the end of src.
+func blockEnd(src string, pos int, closer string) int {
+	if i := strings.Index(src[pos:], closer); i >= 0 {
+		return pos + i + len(closer)
+	}
+	return len(src)
+}
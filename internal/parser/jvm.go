@@ -0,0 +1,76 @@
+package parser
+
+import "strings"
+
+var javaSyntax = cSyntax{
+	textBlocks: true,
+}
+
+var kotlinSyntax = cSyntax{
+	nestedComments: true,
+	textBlocks:     true,
+	rawTextBlocks:  true,
+	templates:      true,
+}
+
+// JavaParser parses Java files for comments
+type JavaParser struct{}
+
+// NewJavaParser creates a new Java parser
+func NewJavaParser() *JavaParser {
+	return &JavaParser{}
+}
+
+// Tokenize splits Java content into lexical tokens
+func (p *JavaParser) Tokenize(content string) []Token {
+	return tokenizeC(content, javaSyntax)
+}
+
+// Parse extracts all comments from Java content
+func (p *JavaParser) Parse(content string) ParseResult {
+	return parseCStyle(content, p.Tokenize(content), "java", jvmDocKind)
+}
+
+// ReplaceComment replaces a comment in the content with new text.
+// Javadoc tags such as @param and @return are kept.
+func (p *JavaParser) ReplaceComment(content string, comment Comment, newText string) string {
+	if comment.Kind == KindDoc {
+		return replaceDocBlock(content, comment, newText)
+	}
+	return replaceCStyleComment(content, comment, newText)
+}
+
+// KotlinParser parses Kotlin files for comments
+type KotlinParser struct{}
+
+// NewKotlinParser creates a new Kotlin parser
+func NewKotlinParser() *KotlinParser {
+	return &KotlinParser{}
+}
+
+// Tokenize splits Kotlin content into lexical tokens
+func (p *KotlinParser) Tokenize(content string) []Token {
+	return tokenizeC(content, kotlinSyntax)
+}
+
+// Parse extracts all comments from Kotlin content
+func (p *KotlinParser) Parse(content string) ParseResult {
+	return parseCStyle(content, p.Tokenize(content), "kotlin", jvmDocKind)
+}
+
+// ReplaceComment replaces a comment in the content with new text.
+// KDoc tags such as @param and @return are kept.
+func (p *KotlinParser) ReplaceComment(content string, comment Comment, newText string) string {
+	if comment.Kind == KindDoc {
+		return replaceDocBlock(content, comment, newText)
+	}
+	return replaceCStyleComment(content, comment, newText)
+}
+
+// jvmDocKind recognizes Javadoc and KDoc /** */ comments
+func jvmDocKind(original string) CommentKind {
+	if strings.HasPrefix(original, "/**") && !strings.HasPrefix(original, "/***") && original != "/**/" {
+		return KindDoc
+	}
+	return KindStandalone
+}
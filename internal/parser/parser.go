@@ -1,13 +1,60 @@
 package parser
 
+import (
+	"regexp"
+	"strings"
+)
+
+// CommentKind classifies the role a comment plays in a file
+type CommentKind int
+
+const (
+	KindStandalone CommentKind = iota // Comment on its own line
+	KindInline                        // Trailing comment after code on the same line
+	KindDoc                           // Doc comment attached to a declaration
+	KindJSDoc                         // /** ... */ JSDoc block
+	KindDocstring                     // Python docstring
+	KindLicense                       // License or copyright header
+	KindDirective                     // Tool directive such as //go:build or # type: ignore
+	KindShebang                       // #! interpreter line
+)
+
+var kindNames = map[CommentKind]string{
+	KindStandalone: "standalone",
+	KindInline:     "inline",
+	KindDoc:        "doc",
+	KindJSDoc:      "jsdoc",
+	KindDocstring:  "docstring",
+	KindLicense:    "license",
+	KindDirective:  "directive",
+	KindShebang:    "shebang",
+}
+
+// String returns the kind's name
+func (k CommentKind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// IsProtected reports whether comments of this kind carry meaning for
+// tools or legal reasons and must never be rewritten or removed
+func (k CommentKind) IsProtected() bool {
+	return k == KindLicense || k == KindDirective || k == KindShebang
+}
+
 // Comment represents a comment found in source code
 type Comment struct {
-	Text       string // The comment text (without delimiters)
-	Start      int    // Start position in the file
-	End        int    // End position in the file
-	LineNumber int    // Line number (1-indexed)
-	IsBlock    bool   // True if block comment (/* */ or """)
-	Original   string // Original text including delimiters
+	Text       string      // The comment text (without delimiters)
+	Start      int         // Start position in the file
+	End        int         // End position in the file
+	LineNumber int         // Line number (1-indexed)
+	IsBlock    bool        // True if block comment (/* */ or """)
+	Original   string      // Original text including delimiters
+	Target     string      // Declaration the comment documents, e.g. "func Parse" (empty if none)
+	Kind       CommentKind // What role the comment plays
+	Suppressed string      // Marker that tells deaiify to leave the comment alone, e.g. "deaiify:ignore"
 }
 
 // ParseResult holds the result of parsing a file
@@ -18,6 +65,143 @@ type ParseResult struct {
 
 // Parser interface for language-specific parsers
 type Parser interface {
+	Tokenize(content string) []Token
 	Parse(content string) ParseResult
 	ReplaceComment(content string, comment Comment, newText string) string
 }
+
+// Encloser is implemented by parsers that can tell from the syntax tree
+// which declaration contains a comment
+type Encloser interface {
+	// Enclosing returns the declaration containing each comment, such as
+	// "func (T) Run", or "" for one outside any. It returns nil if content
+	// does not parse.
+	Enclosing(content string, comments []Comment) []string
+}
+
+// licenseMarkers identify license and copyright headers
+var licenseMarkers = []string{
+	"copyright",
+	"license",
+	"licence",
+	"spdx-license-identifier",
+	"all rights reserved",
+}
+
+// classifyComments assigns kinds that depend on position rather than
+// syntax. Shebangs, directives, license headers and trailing comments take
+// precedence over any language-specific kind the parser already set.
+// codeStart is the position of the first code token; comments up to it
+// form the file header. Suppression markers are applied last.
+func classifyComments(content string, comments []Comment, codeStart int, directives []*regexp.Regexp) {
+	for i := range comments {
+		c := &comments[i]
+		switch {
+		case c.Start == 0 && strings.HasPrefix(c.Original, "#!"):
+			c.Kind = KindShebang
+		case matchesAny(c.Original, directives):
+			c.Kind = KindDirective
+		case c.Start <= codeStart && isLicense(c.Text):
+			c.Kind = KindLicense
+		case isTrailing(content, c.Start):
+			c.Kind = KindInline
+		}
+	}
+	applySuppressions(comments)
+}
+
+// isLicense reports whether text reads like a license or copyright notice
+func isLicense(text string) bool {
+	lower := strings.ToLower(text)
+	for _, marker := range licenseMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTrailing reports whether there is code before pos on its line
+func isTrailing(content string, pos int) bool {
+	return strings.TrimSpace(content[lineStartOf(content, pos):pos]) != ""
+}
+
+// firstCodeStart returns the position of the first non-comment token
+func firstCodeStart(content string, tokens []Token) int {
+	for _, tok := range tokens {
+		if tok.Kind != TokenComment {
+			return tok.Start
+		}
+	}
+	return len(content)
+}
+
+func matchesAny(s string, patterns []*regexp.Regexp) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIntact reports whether content still holds the comment at its
+// recorded position, so a stale comment never rewrites code
+func isIntact(content string, comment Comment) bool {
+	return comment.Start >= 0 && comment.End <= len(content) &&
+		content[comment.Start:comment.End] == comment.Original
+}
+
+// removeComment deletes a comment. If only whitespace precedes it on its
+// line, the whole line goes with it; a trailing comment leaves the code
+// and line break in place.
+func removeComment(content string, comment Comment) string {
+	startIdx := comment.Start
+	endIdx := comment.End
+
+	lineStart := lineStartOf(content, startIdx)
+	if strings.TrimSpace(content[lineStart:startIdx]) == "" {
+		startIdx = lineStart
+		// Remove trailing newline if present
+		if endIdx < len(content) && content[endIdx] == '\r' {
+			endIdx++
+		}
+		if endIdx < len(content) && content[endIdx] == '\n' {
+			endIdx++
+		}
+	} else {
+		// Trailing comment: drop the padding before it but keep the line break
+		for startIdx > lineStart && (content[startIdx-1] == ' ' || content[startIdx-1] == '\t') {
+			startIdx--
+		}
+	}
+
+	return content[:startIdx] + content[endIdx:]
+}
+
+// replaceCStyleComment rewrites a // or /* */ comment, keeping the
+// original opener so doc markers like ///, //!, /** and /*! survive
+func replaceCStyleComment(content string, comment Comment, newText string) string {
+	if !isIntact(content, comment) {
+		return content
+	}
+	if newText == "" {
+		return removeComment(content, comment)
+	}
+
+	opener := comment.Original[:2]
+	if len(comment.Original) > 2 && comment.Original != "/**/" {
+		switch third := comment.Original[2]; {
+		case third == '!', third == '/' && !comment.IsBlock, third == '*' && comment.IsBlock:
+			opener = comment.Original[:3]
+		}
+	}
+
+	var replacement string
+	if comment.IsBlock {
+		replacement = opener + " " + newText + " */"
+	} else {
+		replacement = opener + " " + newText
+	}
+	return content[:comment.Start] + replacement + content[comment.End:]
+}
@@ -0,0 +1,288 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// parseTest is a source file and the comments its parser should find,
+// given as their original text
+type parseTest struct {
+	name    string
+	content string
+	want    []string
+}
+
+func runParseTests(t *testing.T, p Parser, tests []parseTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range p.Parse(tt.content).Comments {
+				got = append(got, c.Original)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() comments = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseShell(t *testing.T) {
+	runParseTests(t, NewShellParser(), []parseTest{
+		{
+			name:    "comment after code",
+			content: "echo hi # greet\n",
+			want:    []string{"# greet"},
+		},
+		{
+			name:    "heredoc body",
+			content: "cat <<EOF\n# not a comment\nEOF\n# after\n",
+			want:    []string{"# after"},
+		},
+		{
+			name:    "indented heredoc with quoted marker",
+			content: "cat <<-'END'\n\t# not a comment\n\tEND\n# after\n",
+			want:    []string{"# after"},
+		},
+		{
+			name:    "shift in arithmetic expansion",
+			content: "x=$((1<<N))\n# after\nN\n",
+			want:    []string{"# after"},
+		},
+		{
+			name:    "shift in arithmetic command",
+			content: "(( x = 1<<N ))\n# after\nN\n",
+			want:    []string{"# after"},
+		},
+		{
+			name:    "nested arithmetic then heredoc",
+			content: "x=$(( $((1<<A)) + (2) ))\ncat <<EOF\n# body\nEOF\n# after\n",
+			want:    []string{"# after"},
+		},
+		{
+			name:    "here-string",
+			content: "cat <<<word # trailing\n# after\n",
+			want:    []string{"# trailing", "# after"},
+		},
+		{
+			name:    "argument count and string length",
+			content: "echo $# ${#x} a#b\n# after\n",
+			want:    []string{"# after"},
+		},
+		{
+			name:    "hash in quotes",
+			content: "echo '# no' \"# no\" $'# no'\n",
+		},
+		{
+			name:    "hash in command substitution in a string",
+			content: "echo \"$(echo '#no')\" # yes\n",
+			want:    []string{"# yes"},
+		},
+	})
+}
+
+func TestParseRuby(t *testing.T) {
+	runParseTests(t, NewRubyParser(), []parseTest{
+		{
+			name:    "begin and end block",
+			content: "x = 1\n=begin\nblock text\n=end\ny = 2 # after\n",
+			want:    []string{"=begin\nblock text\n=end", "# after"},
+		},
+		{
+			name:    "interpolation",
+			content: "puts \"#{x} # no\" # yes\n",
+			want:    []string{"# yes"},
+		},
+		{
+			name:    "heredoc",
+			content: "s = <<~SQL\n  # not a comment\n  SQL\n# after\n",
+			want:    []string{"# after"},
+		},
+		{
+			name:    "percent literal",
+			content: "a = %w(# no) # yes\n",
+			want:    []string{"# yes"},
+		},
+		{
+			name:    "regex literal",
+			content: "x =~ /# no/ # yes\n",
+			want:    []string{"# yes"},
+		},
+		{
+			name:    "end marker",
+			content: "x = 1\n__END__\n# data\n",
+		},
+	})
+}
+
+func TestParsePerl(t *testing.T) {
+	runParseTests(t, NewPerlParser(), []parseTest{
+		{
+			name:    "POD",
+			content: "my $x = 1;\n=pod\n\nDocs here.\n\n=cut\n# after\n",
+			want:    []string{"=pod\n\nDocs here.\n\n=cut", "# after"},
+		},
+		{
+			name:    "last index of an array",
+			content: "print $#array; # yes\n",
+			want:    []string{"# yes"},
+		},
+		{
+			name:    "quote-like operators",
+			content: "my @w = qw(a # b); s{#}{x}g; # yes\n",
+			want:    []string{"# yes"},
+		},
+	})
+}
+
+func TestParseRust(t *testing.T) {
+	runParseTests(t, NewRustParser(), []parseTest{
+		{
+			name:    "nested block comment",
+			content: "/* outer /* inner */ still outer */\nfn main() {}\n",
+			want:    []string{"/* outer /* inner */ still outer */"},
+		},
+		{
+			name:    "raw string",
+			content: "let s = r#\"// \"no\" /* no */\"#; // yes\n",
+			want:    []string{"// yes"},
+		},
+		{
+			name:    "doc comment",
+			content: "/// Adds one\nfn inc(x: i32) -> i32 { x + 1 }\n",
+			want:    []string{"/// Adds one"},
+		},
+		{
+			name:    "lifetime and char",
+			content: "fn f<'a>(s: &'a str) -> char { '/' } // yes\n",
+			want:    []string{"// yes"},
+		},
+	})
+}
+
+func TestParseJava(t *testing.T) {
+	runParseTests(t, NewJavaParser(), []parseTest{
+		{
+			name:    "text block",
+			content: "String s = \"\"\"\n    // not a comment\n    \"\"\"; // yes\n",
+			want:    []string{"// yes"},
+		},
+		{
+			name:    "text block with escaped quotes",
+			content: "String s = \"\"\"\n    \\\"\"\" // no\n    \"\"\";\n",
+		},
+		{
+			name:    "javadoc",
+			content: "/** Adds one. */\nint inc(int x) { return x + 1; }\n",
+			want:    []string{"/** Adds one. */"},
+		},
+	})
+}
+
+func TestParseJavaScript(t *testing.T) {
+	runParseTests(t, NewJavaScriptParser(), []parseTest{
+		{
+			name:    "division",
+			content: "x = a / b / c // yes\n",
+			want:    []string{"// yes"},
+		},
+		{
+			name:    "regex",
+			content: "re = /\\/\\/ no/g // yes\n",
+			want:    []string{"// yes"},
+		},
+		{
+			name:    "regex after return",
+			content: "function f() { return /\\/* no/.test(s) } // yes\n",
+			want:    []string{"// yes"},
+		},
+		{
+			name:    "regex with slash in a class",
+			content: "re = /[/]// no/ // yes\n",
+			want:    []string{"// yes"},
+		},
+		{
+			name:    "division after a closing paren",
+			content: "x = (a) / 2 // yes\n",
+			want:    []string{"// yes"},
+		},
+		{
+			name:    "template literal",
+			content: "s = `// no ${a /* code */ + `// no`}` // yes\n",
+			want:    []string{"/* code */", "// yes"},
+		},
+	})
+}
+
+func TestParsePython(t *testing.T) {
+	runParseTests(t, NewPythonParser(), []parseTest{
+		{
+			name:    "module docstring",
+			content: "\"\"\"Module docs.\"\"\"\nx = 1\n",
+			want:    []string{"\"\"\"Module docs.\"\"\""},
+		},
+		{
+			name:    "function docstring",
+			content: "def f():\n    '''Does f.'''\n    return 1\n",
+			want:    []string{"'''Does f.'''"},
+		},
+		{
+			name:    "assigned triple-quoted string",
+			content: "x = \"\"\"# not a comment\"\"\"\n",
+		},
+		{
+			name:    "triple-quoted string argument",
+			content: "def f():\n    g(\"\"\"text\"\"\")\n",
+		},
+		{
+			name:    "string after the first statement",
+			content: "def f():\n    x = 1\n    \"\"\"not a docstring\"\"\"\n",
+		},
+		{
+			name:    "hash in strings",
+			content: "s = '# no' + f\"{x!r} # no\" # yes\n",
+			want:    []string{"# yes"},
+		},
+	})
+}
+
+func TestParseGo(t *testing.T) {
+	runParseTests(t, NewGoParser(), []parseTest{
+		{
+			name:    "comments in strings",
+			content: "package p\n\nvar s = \"// no\" + `/* no */` // yes\n",
+			want:    []string{"// yes"},
+		},
+		{
+			name:    "doc and block comments",
+			content: "package p\n\n// F does f\nfunc F() { /* inside */ }\n",
+			want:    []string{"// F does f", "/* inside */"},
+		},
+	})
+}
+
+func TestParseKinds(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       Parser
+		content string
+		want    []CommentKind
+	}{
+		{"shell shebang and trailing", NewShellParser(), "#!/bin/sh\necho hi # greet\n", []CommentKind{KindShebang, KindInline}},
+		{"python docstring", NewPythonParser(), "def f():\n    \"\"\"Does f.\"\"\"\n", []CommentKind{KindDocstring}},
+		{"go directive", NewGoParser(), "//go:build linux\n\npackage p\n", []CommentKind{KindDirective}},
+		{"license header", NewRustParser(), "// Copyright 2024 Someone\n\nfn main() {}\n", []CommentKind{KindLicense}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []CommentKind
+			for _, c := range tt.p.Parse(tt.content).Comments {
+				got = append(got, c.Kind)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() kinds = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package parser
+
+import "strings"
+
+// pyOperators lists multi-character Python operators, longest first
+var pyOperators = []string{
+	"**=", "//=", ">>=", "<<=", "...",
+	"**", "//", ">>", "<<", "<=", ">=", "==", "!=", "->", ":=",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "@=",
+}
+
+// pyToken is a Python token with the logical-line information needed to
+// tell docstrings apart from other string statements
+type pyToken struct {
+	Token
+	first  bool // First token of a logical line
+	indent int  // Indentation of the physical line the token starts on
+}
+
+// pyLexer splits Python source into tokens. It understands string
+// prefixes, triple quotes, f-string replacement fields and line
+// continuations so '#' inside strings is never taken for a comment.
+type pyLexer struct {
+	src    string
+	pos    int
+	tokens []pyToken
+}
+
+// tokenizePython returns the tokens in Python content
+func tokenizePython(content string) []pyToken {
+	l := &pyLexer{src: content}
+	l.lex()
+	return l.tokens
+}
+
+func (l *pyLexer) lex() {
+	depth := 0
+	lineStart := true // At the start of a logical line
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		start := l.pos
+
+		switch {
+		case c == '\n':
+			l.pos++
+			if depth == 0 {
+				lineStart = true
+			}
+			continue
+
+		case c == '\\' && l.peek(1) == '\n', c == '\\' && l.peek(1) == '\r' && l.peek(2) == '\n':
+			// Explicit line continuation
+			l.pos += 2
+			if l.src[l.pos-1] == '\r' {
+				l.pos++
+			}
+			continue
+
+		case isSpaceByte(c):
+			l.pos++
+			continue
+
+		case c == '#':
+			l.pos = lineEnd(l.src, l.pos)
+			l.tokens = append(l.tokens, pyToken{Token: Token{Kind: TokenComment, Start: start, End: l.pos}})
+			continue
+
+		case c == '\'' || c == '"':
+			l.lexString(0)
+			l.emit(TokenString, start, &lineStart)
+
+		case isIdentByte(c) && !isDigitByte(c):
+			for l.pos < len(l.src) && isIdentByte(l.src[l.pos]) {
+				l.pos++
+			}
+			if q := l.peek(0); (q == '\'' || q == '"') && isPyStringPrefix(l.src[start:l.pos]) {
+				l.lexString(l.pos - start)
+				l.emit(TokenString, start, &lineStart)
+			} else {
+				l.emit(TokenCode, start, &lineStart)
+			}
+
+		case isDigitByte(c), c == '.' && isDigitByte(l.peek(1)):
+			l.lexNumber()
+			l.emit(TokenCode, start, &lineStart)
+
+		default:
+			switch c {
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				if depth > 0 {
+					depth--
+				}
+			}
+			l.pos += len(matchOperator(l.src, l.pos, pyOperators))
+			l.emit(TokenCode, start, &lineStart)
+		}
+	}
+}
+
+func (l *pyLexer) emit(kind TokenKind, start int, lineStart *bool) {
+	l.tokens = append(l.tokens, pyToken{
+		Token:  Token{Kind: kind, Start: start, End: l.pos},
+		first:  *lineStart,
+		indent: lineIndent(l.src, start),
+	})
+	*lineStart = false
+}
+
+// peek returns the byte n positions ahead, or 0 past the end
+func (l *pyLexer) peek(n int) byte {
+	if l.pos+n < len(l.src) {
+		return l.src[l.pos+n]
+	}
+	return 0
+}
+
+// lexString scans a string literal whose prefix of prefixLen bytes has
+// already been consumed. The position is at the opening quote.
+func (l *pyLexer) lexString(prefixLen int) {
+	prefix := strings.ToLower(l.src[l.pos-prefixLen : l.pos])
+	isFormat := strings.ContainsAny(prefix, "ft")
+	quote := l.src[l.pos : l.pos+1]
+	if strings.HasPrefix(l.src[l.pos:], quote+quote+quote) {
+		quote = quote + quote + quote
+	}
+	l.pos += len(quote)
+
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\\':
+			// Escapes apply even in raw strings as far as the closing quote goes
+			l.pos += 2
+		case strings.HasPrefix(l.src[l.pos:], quote):
+			l.pos += len(quote)
+			return
+		case c == '\n' && len(quote) == 1:
+			// Unterminated single-quoted string
+			return
+		case isFormat && c == '{':
+			if l.peek(1) == '{' {
+				l.pos += 2
+				continue
+			}
+			l.lexReplacementField()
+		default:
+			l.pos++
+		}
+	}
+	l.pos = len(l.src)
+}
+
+// lexReplacementField scans an f-string {expression!conversion:spec} field.
+// Strings inside the expression may reuse the enclosing quote (PEP 701),
+// while quotes in the format spec are literal characters.
+func (l *pyLexer) lexReplacementField() {
+	l.pos++ // '{'
+	depth := 0
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\'' || c == '"':
+			l.lexString(0)
+			continue
+		case isIdentByte(c) && !isDigitByte(c):
+			start := l.pos
+			for l.pos < len(l.src) && isIdentByte(l.src[l.pos]) {
+				l.pos++
+			}
+			if q := l.peek(0); (q == '\'' || q == '"') && isPyStringPrefix(l.src[start:l.pos]) {
+				l.lexString(l.pos - start)
+			}
+			continue
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == '}':
+			if depth == 0 {
+				l.pos++
+				return
+			}
+			depth--
+		case c == ':' && depth == 0:
+			l.pos++
+			l.lexFormatSpec()
+			return
+		}
+		l.pos++
+	}
+}
+
+// lexFormatSpec scans a format spec up to and including the closing '}'
+func (l *pyLexer) lexFormatSpec() {
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '{':
+			l.lexReplacementField()
+			continue
+		case '}':
+			l.pos++
+			return
+		case '\n':
+			return
+		}
+		l.pos++
+	}
+}
+
+// lexNumber scans a numeric literal including exponents and separators
+func (l *pyLexer) lexNumber() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if isIdentByte(c) || c == '.' {
+			l.pos++
+			continue
+		}
+		if (c == '+' || c == '-') && (l.src[l.pos-1] == 'e' || l.src[l.pos-1] == 'E') &&
+			!strings.HasPrefix(strings.ToLower(l.src[:l.pos]), "0x") {
+			l.pos++
+			continue
+		}
+		break
+	}
+}
+
+// isPyStringPrefix reports whether s is a valid string prefix such as r, b, f or rb
+func isPyStringPrefix(s string) bool {
+	switch strings.ToLower(s) {
+	case "r", "u", "b", "f", "t", "br", "rb", "fr", "rf", "tr", "rt":
+		return true
+	}
+	return false
+}
+
+// pyDocstrings returns the indexes of string tokens that are docstrings:
+// the first statement of a module, class or function
+func pyDocstrings(content string, tokens []pyToken) []int {
+	var docstrings []int
+
+	// Group significant tokens into logical lines
+	var lines [][]int
+	for i, tok := range tokens {
+		if tok.Kind == TokenComment {
+			continue
+		}
+		if tok.first || len(lines) == 0 {
+			lines = append(lines, nil)
+		}
+		lines[len(lines)-1] = append(lines[len(lines)-1], i)
+	}
+
+	for n, line := range lines {
+		last := tokens[line[len(line)-1]]
+		if !isPyDocstringLiteral(content, last.Token) {
+			continue
+		}
+
+		switch {
+		case len(line) == 1 && n == 0:
+			// Module docstring
+			docstrings = append(docstrings, line[0])
+
+		case len(line) == 1:
+			// First statement after a def/class header
+			prev := lines[n-1]
+			if isPyDefHeader(content, tokens, prev) && tokens[line[0]].indent > tokens[prev[0]].indent {
+				docstrings = append(docstrings, line[0])
+			}
+
+		case isPyDefHeader(content, tokens, line[:len(line)-1]):
+			// def f(): """docstring""" on a single line
+			docstrings = append(docstrings, line[len(line)-1])
+		}
+	}
+
+	return docstrings
+}
+
+// isPyDocstringLiteral reports whether tok is a string that can be a
+// docstring. Bytes, f-strings and template strings are never docstrings.
+func isPyDocstringLiteral(content string, tok Token) bool {
+	if tok.Kind != TokenString {
+		return false
+	}
+	prefix := strings.ToLower(pyStringPrefix(tok.Text(content)))
+	return !strings.ContainsAny(prefix, "bft")
+}
+
+// isPyDefHeader reports whether the logical line is a def or class header
+// ending in ':'
+func isPyDefHeader(content string, tokens []pyToken, line []int) bool {
+	if len(line) < 2 {
+		return false
+	}
+	first := tokens[line[0]].Text(content)
+	if first == "async" && len(line) > 1 {
+		first = tokens[line[1]].Text(content)
+	}
+	return (first == "def" || first == "class") && tokens[line[len(line)-1]].Text(content) == ":"
+}
+
+// pyStringPrefix returns the prefix letters of a string literal
+func pyStringPrefix(literal string) string {
+	return literal[:strings.IndexAny(literal, `'"`)]
+}
+
+// lineIndent returns the indentation width of the line containing pos
+func lineIndent(src string, pos int) int {
+	lineStart := strings.LastIndexByte(src[:pos], '\n') + 1
+	indent := 0
+	for i := lineStart; i < len(src) && (src[i] == ' ' || src[i] == '\t'); i++ {
+		indent++
+	}
+	return indent
+}
@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"regexp"
 	"strings"
 )
 
@@ -13,98 +12,139 @@ func NewPythonParser() *PythonParser {
 	return &PythonParser{}
 }
 
-var (
-	// Match single-line comments: # ...
-	pyLineCommentRe = regexp.MustCompile(`(?m)#[^\n]*`)
-	// Match docstrings: """ ... """ or ''' ... '''
-	pyDocstringRe = regexp.MustCompile(`(?s)(""".*?"""|'''.*?''')`)
-)
+// Tokenize splits Python content into lexical tokens
+func (p *PythonParser) Tokenize(content string) []Token {
+	pyTokens := tokenizePython(content)
+	tokens := make([]Token, len(pyTokens))
+	for i, tok := range pyTokens {
+		tokens[i] = tok.Token
+	}
+	return tokens
+}
 
-// Parse extracts all comments from Python content
+// Parse extracts all comments from Python content. Triple-quoted strings
+// are only reported when they are docstrings.
 func (p *PythonParser) Parse(content string) ParseResult {
 	var comments []Comment
 
-	// Find line comments
-	for _, match := range pyLineCommentRe.FindAllStringIndex(content, -1) {
-		start, end := match[0], match[1]
-		original := content[start:end]
-		text := strings.TrimPrefix(original, "#")
-		text = strings.TrimSpace(text)
-		lineNum := countLines(content[:start]) + 1
-
-		comments = append(comments, Comment{
-			Text:       text,
-			Start:      start,
-			End:        end,
-			LineNumber: lineNum,
-			IsBlock:    false,
-			Original:   original,
-		})
+	tokens := tokenizePython(content)
+	docstrings := make(map[int]bool)
+	for _, i := range pyDocstrings(content, tokens) {
+		docstrings[i] = true
 	}
 
-	// Find docstrings
-	for _, match := range pyDocstringRe.FindAllStringIndex(content, -1) {
-		start, end := match[0], match[1]
-		original := content[start:end]
-		text := original
-		// Remove triple quotes
-		if strings.HasPrefix(text, `"""`) {
-			text = strings.TrimPrefix(text, `"""`)
-			text = strings.TrimSuffix(text, `"""`)
-		} else {
-			text = strings.TrimPrefix(text, `'''`)
-			text = strings.TrimSuffix(text, `'''`)
+	codeStart := len(content)
+	for i, tok := range tokens {
+		var text string
+		isBlock := false
+		kind := KindStandalone
+
+		if tok.Kind != TokenComment && codeStart == len(content) {
+			codeStart = tok.Start
+		}
+
+		switch {
+		case tok.Kind == TokenComment:
+			text = strings.TrimPrefix(tok.Text(content), "#")
+		case docstrings[i]:
+			_, quote, body := splitPyString(tok.Text(content))
+			text = strings.TrimSuffix(body, quote)
+			isBlock = true
+			kind = KindDocstring
+		default:
+			continue
 		}
-		text = strings.TrimSpace(text)
-		lineNum := countLines(content[:start]) + 1
 
 		comments = append(comments, Comment{
-			Text:       text,
-			Start:      start,
-			End:        end,
-			LineNumber: lineNum,
-			IsBlock:    true,
-			Original:   original,
+			Text:       strings.TrimSpace(text),
+			Start:      tok.Start,
+			End:        tok.End,
+			LineNumber: countLines(content[:tok.Start]) + 1,
+			IsBlock:    isBlock,
+			Original:   tok.Text(content),
+			Kind:       kind,
 		})
 	}
 
+	classifyComments(content, comments, codeStart, Directives("py"))
+
 	return ParseResult{
 		Content:  content,
 		Comments: comments,
 	}
 }
 
-// ReplaceComment replaces a comment in the content with new text
+// ReplaceComment replaces a comment in the content with new text.
+// Docstrings keep their prefix, quote style and layout.
 func (p *PythonParser) ReplaceComment(content string, comment Comment, newText string) string {
-	var replacement string
-	if newText == "" {
-		// Remove the comment entirely
-		startIdx := comment.Start
-		endIdx := comment.End
-
-		// If comment is at start of line (only whitespace before it), remove the whole line
-		lineStart := startIdx
-		for lineStart > 0 && content[lineStart-1] != '\n' {
-			lineStart--
-		}
-		prefix := content[lineStart:startIdx]
-		if strings.TrimSpace(prefix) == "" {
-			// Only whitespace before comment, remove from line start
-			startIdx = lineStart
-		}
+	// Never rewrite anything that isn't the comment we parsed
+	if !isIntact(content, comment) {
+		return content
+	}
 
-		// Remove trailing newline if present
-		if endIdx < len(content) && content[endIdx] == '\n' {
-			endIdx++
+	if newText == "" {
+		// A docstring that is the only statement in its block can't be
+		// dropped without leaving an empty body
+		if comment.IsBlock && pyIsOnlyStatement(content, comment) {
+			return content[:comment.Start] + "pass" + content[comment.End:]
 		}
-
-		return content[:startIdx] + content[endIdx:]
+		return removeComment(content, comment)
 	}
 
+	var replacement string
 	if comment.IsBlock {
-		replacement = `"""` + newText + `"""`
+		prefix, quote, body := splitPyString(comment.Original)
+		if strings.HasPrefix(strings.TrimLeft(body, " \t"), "\n") || strings.HasPrefix(strings.TrimLeft(body, " \t"), "\r\n") {
+			// Multi-line layout: quotes on their own lines, text indented
+			indent := content[lineStartOf(content, comment.Start):comment.Start]
+			indent = indent[:len(indent)-len(strings.TrimLeft(indent, " \t"))]
+			replacement = prefix + quote + "\n" + indent + newText + "\n" + indent + quote
+		} else {
+			replacement = prefix + quote + newText + quote
+		}
 	} else {
 		replacement = "# " + newText
 	}
 	return content[:comment.Start] + replacement + content[comment.End:]
 }
+
+// splitPyString splits a string literal into its prefix, quote and the
+// remainder after the opening quote
+func splitPyString(literal string) (prefix, quote, rest string) {
+	prefix = pyStringPrefix(literal)
+	rest = literal[len(prefix):]
+	quote = rest[:1]
+	if strings.HasPrefix(rest, quote+quote+quote) && len(rest) >= 6 {
+		quote = quote + quote + quote
+	}
+	return prefix, quote, rest[len(quote):]
+}
+
+// pyIsOnlyStatement reports whether the docstring is the only statement of
+// a class or function body
+func pyIsOnlyStatement(content string, comment Comment) bool {
+	lineStart := lineStartOf(content, comment.Start)
+	if strings.TrimSpace(content[lineStart:comment.Start]) != "" {
+		// def f(): """docstring"""
+		return true
+	}
+
+	indent := lineIndent(content, comment.Start)
+	if indent == 0 {
+		// Module docstrings may be removed freely
+		return false
+	}
+
+	for _, tok := range tokenizePython(content) {
+		if tok.Start < comment.End || tok.Kind == TokenComment {
+			continue
+		}
+		return tok.indent < indent
+	}
+	return true
+}
+
+// lineStartOf returns the position of the start of the line containing pos
+func lineStartOf(content string, pos int) int {
+	return strings.LastIndexByte(content[:pos], '\n') + 1
+}
@@ -0,0 +1,30 @@
+package parser
+
+var rustSyntax = cSyntax{
+	nestedComments:   true,
+	multilineStrings: true,
+	rustLiterals:     true,
+}
+
+// RustParser parses Rust files for comments
+type RustParser struct{}
+
+// NewRustParser creates a new Rust parser
+func NewRustParser() *RustParser {
+	return &RustParser{}
+}
+
+// Tokenize splits Rust content into lexical tokens
+func (p *RustParser) Tokenize(content string) []Token {
+	return tokenizeC(content, rustSyntax)
+}
+
+// Parse extracts all comments from Rust content
+func (p *RustParser) Parse(content string) ParseResult {
+	return parseCStyle(content, p.Tokenize(content), "rust", docCommentKind)
+}
+
+// ReplaceComment replaces a comment in the content with new text
+func (p *RustParser) ReplaceComment(content string, comment Comment, newText string) string {
+	return replaceCStyleComment(content, comment, newText)
+}
@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Suppression markers. They work in any comment of any language; the
+// comments holding them are directives, so they are never edited away.
+const (
+	IgnoreMarker         = "deaiify:ignore"           // This comment and others on its lines
+	IgnoreNextLineMarker = "deaiify:ignore-next-line" // Comments starting on the next line
+	IgnoreFileMarker     = "deaiify:ignore-file"      // The whole file
+	DisableMarker        = "deaiify:disable"          // Comments up to the next deaiify:enable
+	EnableMarker         = "deaiify:enable"
+)
+
+// Longer markers come first so the match is not cut short
+var markerRe = regexp.MustCompile(`\bdeaiify:(ignore-next-line|ignore-file|ignore|disable|enable)\b`)
+
+// applySuppressions sets Suppressed on every comment covered by a marker
+func applySuppressions(comments []Comment) {
+	order := make([]int, len(comments))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return comments[order[a]].Start < comments[order[b]].Start
+	})
+
+	// Lines named by ignore and ignore-next-line markers
+	lines := map[int]string{}
+	for _, i := range order {
+		c := comments[i]
+		switch markerRe.FindString(c.Original) {
+		case IgnoreFileMarker:
+			for j := range comments {
+				comments[j].Suppressed = IgnoreFileMarker
+			}
+			return
+		case IgnoreMarker:
+			for line := c.LineNumber; line <= endLine(c); line++ {
+				lines[line] = IgnoreMarker
+			}
+		case IgnoreNextLineMarker:
+			lines[endLine(c)+1] = IgnoreNextLineMarker
+		}
+	}
+
+	disabled := false
+	for _, i := range order {
+		c := &comments[i]
+		switch markerRe.FindString(c.Original) {
+		case DisableMarker:
+			disabled = true
+			continue
+		case EnableMarker:
+			disabled = false
+			continue
+		}
+		if disabled {
+			c.Suppressed = DisableMarker
+		} else if marker, ok := lines[c.LineNumber]; ok {
+			c.Suppressed = marker
+		}
+	}
+}
+
+// IgnoresFile reports whether the comments include deaiify:ignore-file
+func IgnoresFile(comments []Comment) bool {
+	for _, c := range comments {
+		if c.Suppressed == IgnoreFileMarker {
+			return true
+		}
+	}
+	return false
+}
+
+// endLine returns the line a comment ends on
+func endLine(c Comment) int {
+	return c.LineNumber + strings.Count(c.Original, "\n")
+}
@@ -0,0 +1,50 @@
+package parser
+
+// TokenKind classifies a lexical token
+type TokenKind int
+
+const (
+	TokenCode    TokenKind = iota // Identifiers, keywords, numbers and punctuation
+	TokenString                   // String, template, regex and JSX text literals
+	TokenComment                  // Line and block comments
+)
+
+// Token is a lexical token found in source code
+type Token struct {
+	Kind  TokenKind
+	Start int // Start position in the file
+	End   int // End position in the file
+}
+
+// Text returns the token's source text
+func (t Token) Text(content string) string {
+	return content[t.Start:t.End]
+}
+
+// isIdentByte reports whether b can appear in an identifier. Bytes >= 0x80
+// are treated as identifier bytes so UTF-8 names stay in one token.
+func isIdentByte(b byte) bool {
+	return b == '_' || b == '$' || b >= 0x80 ||
+		(b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+// isSpaceByte reports whether b is insignificant whitespace
+func isSpaceByte(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f' || b == '\v'
+}
+
+// isDigitByte reports whether b is an ASCII digit
+func isDigitByte(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// matchOperator returns the longest operator from ops found at src[pos:],
+// or a single byte if none match.
+func matchOperator(src string, pos int, ops []string) string {
+	for _, op := range ops {
+		if len(src)-pos >= len(op) && src[pos:pos+len(op)] == op {
+			return op
+		}
+	}
+	return src[pos : pos+1]
+}
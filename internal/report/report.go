@@ -0,0 +1,294 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"deaiify/internal/detector"
+	"deaiify/internal/parser"
+	"deaiify/internal/transformer"
+)
+
+// SchemaVersion identifies the report layout. It changes whenever a field
+// is removed or changes meaning; new fields may appear without a bump.
+const SchemaVersion = 1
+
+// Report is the machine-readable result of a run
+type Report struct {
+	SchemaVersion int       `json:"schema_version"`
+	Tool          string    `json:"tool"`
+	Created       time.Time `json:"created"`
+	Seed          int64     `json:"seed"`
+	Summary       Summary   `json:"summary"`
+	Files         []File    `json:"files"`
+	Commits       []Commit  `json:"commits,omitempty"`
+}
+
+// Summary totals the files in a report
+type Summary struct {
+	Files           int     `json:"files"`
+	Comments        int     `json:"comments"`
+	AIComments      int     `json:"ai_comments"`
+	Baselined       int     `json:"baselined,omitempty"` // AI-like comments listed in the baseline
+	Transformations int     `json:"transformations"`
+	Score           float64 `json:"score"` // Mean score of the comments not in the baseline, 0.0 to 1.0
+
+	scoreSum float64
+}
+
+// File is one file's detection results and the edits made to it
+type File struct {
+	Path            string    `json:"path"` // Slash-separated
+	Language        string    `json:"language"`
+	Score           float64   `json:"score"` // Mean score of the comments not in the baseline
+	TotalComments   int       `json:"total_comments"`
+	AIComments      int       `json:"ai_comments"`
+	Baselined       int       `json:"baselined,omitempty"` // AI-like comments listed in the baseline
+	Transformations int       `json:"transformations"`     // Comment, typo and formatting edits
+	Skipped         string    `json:"skipped,omitempty"`   // Why the file's edits were not made
+	Comments        []Comment `json:"comments"`
+}
+
+// Comment is one comment with its score and what was done with it
+type Comment struct {
+	Region
+	Kind        string   `json:"kind"`
+	Text        string   `json:"text"`
+	Score       float64  `json:"score"`
+	AILike      bool     `json:"ai_like"`
+	Reasons     []string `json:"reasons"`
+	Rules       []string `json:"rules"`                 // Rule ID of each reason
+	Action      string   `json:"action,omitempty"`      // "removed", "replaced", "typo_injected" or "kept"
+	Replacement string   `json:"replacement,omitempty"` // New comment text
+	Fix         *Fix     `json:"fix,omitempty"`         // The edit made to the file for this comment
+	Suppressed  string   `json:"suppressed,omitempty"`  // Marker that suppressed the comment
+	Baselined   bool     `json:"baselined,omitempty"`   // Listed in the baseline, so not a new finding
+}
+
+// Fix replaces a region of the original file with new text
+type Fix struct {
+	Region
+	Text string `json:"text"`
+}
+
+// Commit is a commit whose message looks AI-written
+type Commit struct {
+	Hash    string   `json:"hash"`
+	Subject string   `json:"subject"`
+	Reasons []string `json:"reasons"`
+	Rules   []string `json:"rules"`
+}
+
+// Region is a span of a file. Lines and columns start at 1, columns count
+// Unicode code points, and the end column is just past the last character.
+type Region struct {
+	StartLine   int `json:"start_line"`
+	StartColumn int `json:"start_column"`
+	EndLine     int `json:"end_line"`
+	EndColumn   int `json:"end_column"`
+}
+
+// New starts an empty report
+func New(seed int64) *Report {
+	return &Report{
+		SchemaVersion: SchemaVersion,
+		Tool:          "deaiify",
+		Created:       time.Now().UTC(),
+		Seed:          seed,
+		Files:         []File{},
+	}
+}
+
+// Add appends a file and updates the summary
+func (r *Report) Add(f File) {
+	r.Files = append(r.Files, f)
+	r.Summary.Files++
+	r.Summary.Comments += f.TotalComments
+	r.Summary.AIComments += f.AIComments
+	r.Summary.Baselined += f.Baselined
+	r.Summary.Transformations += f.Transformations
+	r.Summary.scoreSum += f.Score * float64(f.TotalComments-f.Baselined)
+	if n := r.Summary.Comments - r.Summary.Baselined; n > 0 {
+		r.Summary.Score = r.Summary.scoreSum / float64(n)
+	}
+}
+
+// Write writes the report as indented JSON
+func (r *Report) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// NewFile builds a file's entry from the scores of its comments in content
+// and the results of TransformComments and the following InjectTypos. p
+// recreates each comment's edit for its Fix.
+func NewFile(path, language, content string, p parser.Parser, score detector.FileScore, transforms, typos []transformer.TransformResult) File {
+	f := File{
+		Path:          path,
+		Language:      language,
+		Score:         score.Score,
+		TotalComments: score.TotalComments,
+		AIComments:    score.AIComments,
+		Comments:      make([]Comment, 0, len(score.Details)),
+	}
+
+	for _, cs := range score.Details {
+		f.Comments = append(f.Comments, Comment{
+			Region:     RegionOf(content, cs.Comment.Start, cs.Comment.End),
+			Kind:       cs.Comment.Kind.String(),
+			Text:       cs.Comment.Text,
+			Score:      cs.Result.Score,
+			AILike:     cs.Result.IsAILike,
+			Reasons:    cs.Result.Reasons,
+			Rules:      cs.Result.Rules,
+			Suppressed: cs.Comment.Suppressed,
+		})
+	}
+
+	// Comment transforms report positions in the original content
+	byStart := make(map[int]int, len(score.Details))
+	for i, cs := range score.Details {
+		byStart[cs.Comment.Start] = i
+	}
+	for _, r := range transforms {
+		if i, ok := byStart[r.Start]; ok {
+			f.Comments[i].Action = r.Action
+			f.Comments[i].Replacement = r.Replacement
+		}
+	}
+
+	f.matchTypos(score, typos)
+
+	for i, cs := range score.Details {
+		c := &f.Comments[i]
+		if c.Action == "" || c.Action == "kept" {
+			continue
+		}
+		c.Fix = fixFor(content, p.ReplaceComment(content, cs.Comment, c.Replacement))
+	}
+	return f
+}
+
+// MarkBaselined marks the i'th comment as listed in the baseline and
+// leaves it out of the file's score
+func (f *File) MarkBaselined(i int) {
+	if f.Comments[i].Baselined {
+		return
+	}
+	f.Comments[i].Baselined = true
+	f.Baselined++
+
+	var sum float64
+	count := 0
+	for _, c := range f.Comments {
+		if !c.Baselined {
+			sum += c.Score
+			count++
+		}
+	}
+	f.Score = 0
+	if count > 0 {
+		f.Score = sum / float64(count)
+	}
+}
+
+// Reject records why the file's edits were not made and drops them, so
+// the report offers no fix the tool itself refused
+func (f *File) Reject(reason string) {
+	f.Skipped = reason
+	f.Transformations = 0
+	for i := range f.Comments {
+		c := &f.Comments[i]
+		c.Action, c.Replacement, c.Fix = "", "", nil
+	}
+}
+
+// fixFor returns the smallest edit that turns before into after
+func fixFor(before, after string) *Fix {
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix &&
+		before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+	// Keep the edit on character boundaries
+	for prefix > 0 && prefix < len(before) && !utf8.RuneStart(before[prefix]) {
+		prefix--
+	}
+	for suffix > 0 && !utf8.RuneStart(before[len(before)-suffix]) {
+		suffix--
+	}
+	return &Fix{
+		Region: RegionOf(before, prefix, len(before)-suffix),
+		Text:   after[prefix : len(after)-suffix],
+	}
+}
+
+// AddCommit appends a suspicious commit
+func (r *Report) AddCommit(c Commit) {
+	r.Commits = append(r.Commits, c)
+}
+
+// matchTypos attributes typo results to comments. Typos were found after
+// the comment transforms moved text around, so they are matched in order
+// against the text each surviving comment had at that point.
+func (f *File) matchTypos(score detector.FileScore, typos []transformer.TransformResult) {
+	order := make([]int, len(score.Details))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return score.Details[order[a]].Comment.Start < score.Details[order[b]].Comment.Start
+	})
+	sorted := append([]transformer.TransformResult(nil), typos...)
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a].Start < sorted[b].Start })
+
+	next := 0
+	for _, r := range sorted {
+		for ; next < len(order); next++ {
+			i := order[next]
+			c := &f.Comments[i]
+			var matches bool
+			switch c.Action {
+			case "", "kept":
+				matches = score.Details[i].Comment.Original == r.Original
+			case "replaced":
+				matches = strings.Contains(r.Original, c.Replacement)
+			}
+			if !matches {
+				continue
+			}
+			if c.Action == "replaced" && r.Action == "typo_injected" {
+				c.Replacement = r.Replacement
+			} else {
+				c.Action = r.Action
+				c.Replacement = r.Replacement
+			}
+			next++
+			break
+		}
+	}
+}
+
+// RegionOf returns the region of content[start:end]
+func RegionOf(content string, start, end int) Region {
+	startLine, startCol := position(content, start)
+	endLine, endCol := position(content, end)
+	return Region{StartLine: startLine, StartColumn: startCol, EndLine: endLine, EndColumn: endCol}
+}
+
+// position returns the 1-based line and column of offset pos
+func position(content string, pos int) (int, int) {
+	before := content[:pos]
+	line := strings.Count(before, "\n") + 1
+	lineStart := strings.LastIndex(before, "\n") + 1
+	return line, utf8.RuneCountInString(before[lineStart:]) + 1
+}
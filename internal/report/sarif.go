@@ -0,0 +1,244 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"deaiify/internal/detector"
+	"deaiify/internal/git"
+)
+
+// SARIF 2.1.0, limited to the properties deaiify fills in
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	srcRoot      = "SRCROOT"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                   `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLoc `json:"originalUriBaseIds,omitempty"`
+	ColumnKind         string                      `json:"columnKind"`
+	Results            []sarifResult               `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	DefaultConfig    sarifConfig  `json:"defaultConfiguration"`
+}
+
+type sarifConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	RuleIndex  int             `json:"ruleIndex"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Fixes      []sarifFix      `json:"fixes,omitempty"`
+	Properties map[string]any  `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysical `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogical `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysical struct {
+	ArtifactLocation sarifArtifactLoc `json:"artifactLocation"`
+	Region           *sarifRegion     `json:"region,omitempty"`
+}
+
+type sarifLogical struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+type sarifArtifactLoc struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLoc   `json:"artifactLocation"`
+	Replacements     []sarifReplacement `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion   `json:"deletedRegion"`
+	InsertedContent *sarifMessage `json:"insertedContent,omitempty"`
+}
+
+// sarifRules describes every rule a result can refer to
+var sarifRules = []sarifRule{
+	rule(detector.RuleAIPrefix, "Comment opens with a phrase typical of AI assistants"),
+	rule(detector.RuleEmoji, "Comment contains emoji"),
+	rule(detector.RuleVerbose, "Single-line comment is overly verbose"),
+	rule(detector.RuleFormal, "Comment uses overly formal language"),
+	rule(detector.RuleCapitalization, "Comment has excessive capitalization"),
+	rule(git.RuleCommitEmoji, "Commit message contains emoji"),
+	rule(git.RuleCommitGitmoji, "Commit message contains gitmoji"),
+	rule(git.RuleCommitFooter, "Commit message has an AI attribution footer"),
+	rule(git.RuleCommitPhrasing, "Commit message uses AI-like phrasing"),
+}
+
+func rule(id, description string) sarifRule {
+	return sarifRule{ID: id, ShortDescription: sarifMessage{description}, DefaultConfig: sarifConfig{"warning"}}
+}
+
+// ruleIndex returns the position of a rule in sarifRules
+func ruleIndex(id string) int {
+	for i, r := range sarifRules {
+		if r.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// WriteSARIF writes the report as a SARIF 2.1.0 log with one result per
+// AI-like comment not in the baseline and per suspicious commit. File
+// paths are resolved against root, the directory they are relative to.
+func (r *Report) WriteSARIF(w io.Writer, root string) error {
+	run := sarifRun{
+		Tool:       sarifTool{Driver: sarifDriver{Name: r.Tool, Rules: sarifRules}},
+		ColumnKind: "unicodeCodePoints",
+		Results:    []sarifResult{},
+	}
+	if abs, err := filepath.Abs(root); err == nil {
+		u := url.URL{Scheme: "file", Path: filepath.ToSlash(abs) + "/"}
+		run.OriginalURIBaseIDs = map[string]sarifArtifactLoc{srcRoot: {URI: u.String()}}
+	}
+
+	for _, f := range r.Files {
+		artifact := sarifArtifactLoc{URI: (&url.URL{Path: f.Path}).String(), URIBaseID: srcRoot}
+		for _, c := range f.Comments {
+			if !c.AILike || c.Baselined || len(c.Rules) == 0 {
+				continue
+			}
+			region := toSARIF(c.Region)
+			res := sarifResult{
+				RuleID:    c.Rules[0],
+				RuleIndex: ruleIndex(c.Rules[0]),
+				Level:     "warning",
+				Message:   sarifMessage{"Comment looks AI-written: " + strings.Join(c.Reasons, "; ")},
+				Locations: []sarifLocation{{PhysicalLocation: &sarifPhysical{ArtifactLocation: artifact, Region: &region}}},
+				Properties: map[string]any{
+					"score": c.Score,
+					"rules": c.Rules,
+					"kind":  c.Kind,
+				},
+			}
+			if c.Fix != nil {
+				res.Fixes = []sarifFix{{
+					Description: sarifMessage{fixDescription(c)},
+					ArtifactChanges: []sarifArtifactChange{{
+						ArtifactLocation: artifact,
+						Replacements: []sarifReplacement{{
+							DeletedRegion:   toSARIF(c.Fix.Region),
+							InsertedContent: &sarifMessage{c.Fix.Text},
+						}},
+					}},
+				}}
+			}
+			run.Results = append(run.Results, res)
+		}
+	}
+
+	// Code scanning uploads need a file for every result, so commits are
+	// placed on the first line of the file git writes messages to
+	message := sarifArtifactLoc{URI: commitMessageURI(root), URIBaseID: srcRoot}
+	for _, c := range r.Commits {
+		if len(c.Rules) == 0 {
+			continue
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    c.Rules[0],
+			RuleIndex: ruleIndex(c.Rules[0]),
+			Level:     "warning",
+			Message:   sarifMessage{fmt.Sprintf("Commit %s looks AI-written: %s", c.Hash, strings.Join(c.Reasons, "; "))},
+			Locations: []sarifLocation{{
+				PhysicalLocation: &sarifPhysical{ArtifactLocation: message, Region: &sarifRegion{StartLine: 1}},
+				LogicalLocations: []sarifLogical{{Name: c.Hash, Kind: "commit"}},
+			}},
+			Properties: map[string]any{
+				"subject": c.Subject,
+				"rules":   c.Rules,
+			},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}})
+}
+
+// commitMessageURI returns the path of .git/COMMIT_EDITMSG relative to
+// root, the top of its repository if root is below it
+func commitMessageURI(root string) string {
+	path := ".git/COMMIT_EDITMSG"
+	top, err := git.TopLevel(root)
+	if err != nil {
+		return path
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return path
+	}
+	if real, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = real
+	}
+	if rel, err := filepath.Rel(abs, filepath.Join(top, ".git", "COMMIT_EDITMSG")); err == nil {
+		path = filepath.ToSlash(rel)
+	}
+	return (&url.URL{Path: path}).String()
+}
+
+func toSARIF(r Region) sarifRegion {
+	return sarifRegion{StartLine: r.StartLine, StartColumn: r.StartColumn, EndLine: r.EndLine, EndColumn: r.EndColumn}
+}
+
+func fixDescription(c Comment) string {
+	if c.Action == "removed" {
+		return "Remove comment"
+	}
+	return fmt.Sprintf("Replace comment with %q", c.Replacement)
+}
@@ -1,7 +1,7 @@
 package transformer
 
 import (
-	"math/rand"
+	"fmt"
 	"sort"
 
 	"deaiify/internal/detector"
@@ -37,10 +37,11 @@ type TransformResult struct {
 	Replacement string
 	Action      string // "removed", "replaced", "typo_injected", "kept"
 	LineNumber  int
+	Start       int // Position of the comment in the content it was found in
 }
 
 // TransformComments processes AI-detected comments and humanizes them
-func TransformComments(content string, p parser.Parser, scores []detector.CommentScore, isPython bool) (string, []TransformResult) {
+func (t *Transformer) TransformComments(content string, p parser.Parser, scores []detector.CommentScore) (string, []TransformResult) {
 	var results []TransformResult
 
 	// Sort by position (Start) in descending order so we process from end to start
@@ -51,16 +52,24 @@ func TransformComments(content string, p parser.Parser, scores []detector.Commen
 
 	// Process comments in reverse position order
 	for _, cs := range scores {
-		if !cs.Result.IsAILike {
+		if !cs.Result.IsAILike || cs.Comment.Kind.IsProtected() || cs.Comment.Suppressed != "" {
 			continue
 		}
 
-		action := decideAction(cs)
+		action := t.decideAction(cs)
+		if action == "keep" && t.Reviewer != nil {
+			// Let the reviewer judge every flagged comment
+			action = "remove"
+		}
+		if action == "replace" && len(t.HumanComments) == 0 {
+			action = "remove"
+		}
 		var newContent string
 		var result TransformResult
 
 		result.Original = cs.Comment.Original
 		result.LineNumber = cs.Comment.LineNumber
+		result.Start = cs.Comment.Start
 
 		switch action {
 		case "remove":
@@ -69,7 +78,7 @@ func TransformComments(content string, p parser.Parser, scores []detector.Commen
 			result.Replacement = ""
 
 		case "replace":
-			replacement := pickHumanComment(isPython)
+			replacement := t.pickHumanComment()
 			newContent = p.ReplaceComment(content, cs.Comment, replacement)
 			result.Action = "replaced"
 			result.Replacement = replacement
@@ -79,6 +88,29 @@ func TransformComments(content string, p parser.Parser, scores []detector.Commen
 			newContent = content
 		}
 
+		if result.Action != "kept" {
+			v := t.review(Edit{
+				LineNumber:  cs.Comment.LineNumber,
+				Description: describeCommentEdit(result.Replacement),
+				Before:      content,
+				After:       newContent,
+				Editable:    true,
+			})
+			switch v.Decision {
+			case Reject:
+				result.Action = "kept"
+				result.Replacement = ""
+				newContent = content
+			case Replace:
+				newContent = p.ReplaceComment(content, cs.Comment, v.Text)
+				result.Action = "replaced"
+				if v.Text == "" {
+					result.Action = "removed"
+				}
+				result.Replacement = v.Text
+			}
+		}
+
 		content = newContent
 		results = append(results, result)
 	}
@@ -86,9 +118,17 @@ func TransformComments(content string, p parser.Parser, scores []detector.Commen
 	return content, results
 }
 
+// describeCommentEdit describes a comment removal or replacement for review
+func describeCommentEdit(replacement string) string {
+	if replacement == "" {
+		return "remove comment"
+	}
+	return fmt.Sprintf("replace comment with %q", replacement)
+}
+
 // decideAction randomly decides what to do with an AI comment
-func decideAction(cs detector.CommentScore) string {
-	r := rand.Float64()
+func (t *Transformer) decideAction(cs detector.CommentScore) string {
+	r := t.rand.Float64()
 
 	// Higher score = more aggressive transformation
 	if cs.Result.Score > 0.7 {
@@ -119,11 +159,11 @@ func decideAction(cs detector.CommentScore) string {
 }
 
 // pickHumanComment selects a random human-style comment
-func pickHumanComment(isPython bool) string {
-	comment := HumanComments[rand.Intn(len(HumanComments))]
+func (t *Transformer) pickHumanComment() string {
+	comment := t.HumanComments[t.rand.Intn(len(t.HumanComments))]
 
 	// Occasionally make it lowercase or add punctuation
-	if rand.Float64() < 0.3 {
+	if t.rand.Float64() < 0.3 {
 		comment = comment + "..."
 	}
 
@@ -131,14 +171,14 @@ func pickHumanComment(isPython bool) string {
 }
 
 // shortenComment reduces a verbose comment to just a few words
-func shortenComment(text string) string {
+func (t *Transformer) shortenComment(text string) string {
 	words := splitWords(text)
 	if len(words) <= 3 {
 		return text
 	}
 
 	// Take first 2-4 words
-	n := 2 + rand.Intn(3)
+	n := 2 + t.rand.Intn(3)
 	if n > len(words) {
 		n = len(words)
 	}
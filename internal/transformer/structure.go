@@ -1,7 +1,6 @@
 package transformer
 
 import (
-	"math/rand"
 	"regexp"
 	"strings"
 )
@@ -13,48 +12,44 @@ type StructureTransformResult struct {
 	LineNumber  int
 }
 
-// TransformStructure applies formatting inconsistencies
-func TransformStructure(content string, isJS bool) (string, []StructureTransformResult) {
-	var results []StructureTransformResult
-
-	if isJS {
-		content, results = transformJSStructure(content, results)
-	} else {
-		content, results = transformPyStructure(content, results)
-	}
-
-	return content, results
-}
+// StructureFunc applies a language's formatting inconsistencies
+type StructureFunc func(t *Transformer, content string) (string, []StructureTransformResult)
 
-// transformJSStructure applies JS/TS specific transformations
-func transformJSStructure(content string, results []StructureTransformResult) (string, []StructureTransformResult) {
+// BraceStructure applies transformations for brace languages such as
+// JS/TS, Java and C
+func (t *Transformer) BraceStructure(content string) (string, []StructureTransformResult) {
+	var results []StructureTransformResult
 	lines := strings.Split(content, "\n")
 
 	for i := 0; i < len(lines); i++ {
 		line := lines[i]
 
 		// Randomly remove trailing comma (10% chance)
-		if rand.Float64() < 0.1 {
-			if newLine, changed := removeTrailingComma(line); changed {
-				lines[i] = newLine
-				results = append(results, StructureTransformResult{
-					Type:        "trailing_comma",
-					Description: "removed trailing comma",
-					LineNumber:  i + 1,
-				})
+		if t.rand.Float64() < 0.1 {
+			if newLine, changed := removeTrailingComma(line, ")]}"); changed {
+				var ok bool
+				if lines, ok = t.proposeLines(lines, replaceLine(lines, i, newLine), i+1, "remove trailing comma"); ok {
+					results = append(results, StructureTransformResult{
+						Type:        "trailing_comma",
+						Description: "removed trailing comma",
+						LineNumber:  i + 1,
+					})
+				}
 			}
 		}
 
 		// Randomly add extra blank line after closing brace (5% chance)
-		if rand.Float64() < 0.05 && strings.TrimSpace(line) == "}" {
+		if t.rand.Float64() < 0.05 && strings.TrimSpace(line) == "}" {
 			if i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
-				lines = insertLine(lines, i+1, "")
-				results = append(results, StructureTransformResult{
-					Type:        "blank_line",
-					Description: "added extra blank line",
-					LineNumber:  i + 1,
-				})
-				i++ // Skip the inserted line
+				var ok bool
+				if lines, ok = t.proposeLines(lines, insertLine(lines, i+1, ""), i+1, "add blank line"); ok {
+					results = append(results, StructureTransformResult{
+						Type:        "blank_line",
+						Description: "added extra blank line",
+						LineNumber:  i + 1,
+					})
+					i++ // Skip the inserted line
+				}
 			}
 		}
 
@@ -65,36 +60,44 @@ func transformJSStructure(content string, results []StructureTransformResult) (s
 	return strings.Join(lines, "\n"), results
 }
 
-// transformPyStructure applies Python specific transformations
-func transformPyStructure(content string, results []StructureTransformResult) (string, []StructureTransformResult) {
+// PythonStructure applies Python specific transformations
+func (t *Transformer) PythonStructure(content string) (string, []StructureTransformResult) {
+	var results []StructureTransformResult
 	lines := strings.Split(content, "\n")
 
 	for i := 0; i < len(lines); i++ {
 		line := lines[i]
 
 		// Randomly add extra blank line after function def (5% chance)
-		if rand.Float64() < 0.05 && strings.HasPrefix(strings.TrimSpace(line), "def ") {
+		if t.rand.Float64() < 0.05 && strings.HasPrefix(strings.TrimSpace(line), "def ") {
 			// Check if next line isn't already blank
 			if i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
-				lines = insertLine(lines, i+1, "")
-				results = append(results, StructureTransformResult{
-					Type:        "blank_line",
-					Description: "added blank line after function",
-					LineNumber:  i + 1,
-				})
-				i++
+				var ok bool
+				if lines, ok = t.proposeLines(lines, insertLine(lines, i+1, ""), i+1, "add blank line after function"); ok {
+					results = append(results, StructureTransformResult{
+						Type:        "blank_line",
+						Description: "added blank line after function",
+						LineNumber:  i + 1,
+					})
+					// The def line has no trailing comma, so skip ahead
+					i++
+					continue
+				}
 			}
 		}
 
-		// Randomly remove trailing comma in lists/dicts (10% chance)
-		if rand.Float64() < 0.1 {
-			if newLine, changed := removeTrailingComma(line); changed {
-				lines[i] = newLine
-				results = append(results, StructureTransformResult{
-					Type:        "trailing_comma",
-					Description: "removed trailing comma",
-					LineNumber:  i + 1,
-				})
+		// Randomly remove trailing comma in lists/dicts (10% chance).
+		// Never before ')': (x,) is a tuple and (x) is not.
+		if t.rand.Float64() < 0.1 {
+			if newLine, changed := removeTrailingComma(line, "]}"); changed {
+				var ok bool
+				if lines, ok = t.proposeLines(lines, replaceLine(lines, i, newLine), i+1, "remove trailing comma"); ok {
+					results = append(results, StructureTransformResult{
+						Type:        "trailing_comma",
+						Description: "removed trailing comma",
+						LineNumber:  i + 1,
+					})
+				}
 			}
 		}
 	}
@@ -102,12 +105,11 @@ func transformPyStructure(content string, results []StructureTransformResult) (s
 	return strings.Join(lines, "\n"), results
 }
 
-// removeTrailingComma removes trailing comma before ) or ]
-func removeTrailingComma(line string) (string, bool) {
+// removeTrailingComma removes a trailing comma before one of closers
+func removeTrailingComma(line string, closers string) (string, bool) {
 	trimmed := strings.TrimRight(line, " \t")
-	patterns := []string{",)", ",]", ",}"}
-
-	for _, p := range patterns {
+	for _, closer := range closers {
+		p := "," + string(closer)
 		if strings.HasSuffix(trimmed, p) {
 			return strings.TrimSuffix(trimmed, p) + p[1:], true
 		}
@@ -116,7 +118,7 @@ func removeTrailingComma(line string) (string, bool) {
 }
 
 // tweakOperatorSpacing randomly adjusts spacing around operators
-func tweakOperatorSpacing(line string) (string, bool) {
+func (t *Transformer) tweakOperatorSpacing(line string) (string, bool) {
 	// Only target simple assignments not in strings
 	// This is a simplified heuristic
 
@@ -125,7 +127,7 @@ func tweakOperatorSpacing(line string) (string, bool) {
 	if re.MatchString(line) {
 		// Randomly pick a style
 		styles := []string{"$1=$2", "$1 = $2", "$1= $2", "$1 =$2"}
-		style := styles[rand.Intn(len(styles))]
+		style := styles[t.rand.Intn(len(styles))]
 
 		// Only change once per line
 		newLine := re.ReplaceAllString(line, style)
@@ -137,6 +139,31 @@ func tweakOperatorSpacing(line string) (string, bool) {
 	return line, false
 }
 
+// proposeLines offers the change from lines to proposed for review and
+// returns the lines to continue with and whether the change was accepted
+func (t *Transformer) proposeLines(lines, proposed []string, lineNumber int, description string) ([]string, bool) {
+	if t.Reviewer == nil {
+		return proposed, true
+	}
+	v := t.review(Edit{
+		LineNumber:  lineNumber,
+		Description: description,
+		Before:      strings.Join(lines, "\n"),
+		After:       strings.Join(proposed, "\n"),
+	})
+	if v.Decision == Reject {
+		return lines, false
+	}
+	return proposed, true
+}
+
+// replaceLine returns a copy of lines with line pos replaced
+func replaceLine(lines []string, pos int, newLine string) []string {
+	result := append([]string(nil), lines...)
+	result[pos] = newLine
+	return result
+}
+
 // insertLine inserts a new line at the given position
 func insertLine(lines []string, pos int, newLine string) []string {
 	result := make([]string, len(lines)+1)
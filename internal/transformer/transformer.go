@@ -0,0 +1,98 @@
+package transformer
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"path/filepath"
+)
+
+// Randomizer is the source of every random choice a Transformer makes.
+// *rand.Rand satisfies it.
+type Randomizer interface {
+	Float64() float64
+	Intn(n int) int
+}
+
+// Transformer applies the randomized comment and structure edits. When
+// Reviewer is set, every edit is proposed to it before being applied.
+// The vocabulary fields start as the package defaults.
+type Transformer struct {
+	Reviewer Reviewer
+
+	HumanComments   []string          // Replacements for AI-like comments; empty only removes
+	Typos           map[string]string // Correct spelling to typo
+	TypoProbability float64           // Chance each comment gets a typo
+
+	rand     Randomizer
+	skipRest bool // The reviewer chose to skip the rest of the file
+}
+
+// Decision is a reviewer's answer to a proposed edit
+type Decision int
+
+const (
+	Accept   Decision = iota // Apply the edit as proposed
+	Reject                   // Leave the code as it is
+	Replace                  // Use the reviewer's text instead of the proposed comment
+	SkipFile                 // Reject this and every remaining edit in the file
+)
+
+// Verdict is a reviewer's decision and, for Replace, the text to use
+type Verdict struct {
+	Decision Decision
+	Text     string // Replacement comment text; empty removes the comment
+}
+
+// Edit is a proposed change shown to a Reviewer
+type Edit struct {
+	LineNumber  int
+	Description string // What the edit does, e.g. "remove comment"
+	Before      string // Whole content before the edit
+	After       string // Whole content after the edit
+	Editable    bool   // Whether the reviewer may supply its own comment text
+}
+
+// Reviewer decides whether each proposed edit is applied
+type Reviewer interface {
+	Review(e Edit) Verdict
+}
+
+// New creates a Transformer that draws its choices from r
+func New(r Randomizer) *Transformer {
+	return &Transformer{
+		HumanComments:   HumanComments,
+		Typos:           TypoMap,
+		TypoProbability: TypoProbability,
+		rand:            r,
+	}
+}
+
+// ForFile creates a Transformer whose choices for path depend only on
+// seed and the path, not on which other files are processed
+func ForFile(seed int64, path string) *Transformer {
+	return New(rand.New(rand.NewSource(FileSeed(seed, path))))
+}
+
+// FileSeed derives a per-file seed from the run's seed and a file path
+func FileSeed(seed int64, path string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(filepath.ToSlash(filepath.Clean(path))))
+	return seed ^ int64(h.Sum64())
+}
+
+// review proposes an edit to the reviewer. After SkipFile every edit is
+// rejected without asking.
+func (t *Transformer) review(e Edit) Verdict {
+	if t.skipRest {
+		return Verdict{Decision: Reject}
+	}
+	if t.Reviewer == nil {
+		return Verdict{Decision: Accept}
+	}
+	v := t.Reviewer.Review(e)
+	if v.Decision == SkipFile {
+		t.skipRest = true
+		v.Decision = Reject
+	}
+	return v
+}
@@ -1,7 +1,6 @@
 package transformer
 
 import (
-	"math/rand"
 	"sort"
 	"strings"
 
@@ -41,11 +40,21 @@ var TypoMap = map[string]string{
 	"argument":    "arguement",
 }
 
+// typoWords returns the words with typos in sorted order
+func (t *Transformer) typoWords() []string {
+	words := make([]string, 0, len(t.Typos))
+	for word := range t.Typos {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
 // TypoProbability is the chance a comment gets a typo (5%)
 const TypoProbability = 0.05
 
 // InjectTypos randomly adds typos to comments (not code!)
-func InjectTypos(content string, comments []parser.Comment, p parser.Parser) (string, []TransformResult) {
+func (t *Transformer) InjectTypos(content string, comments []parser.Comment, p parser.Parser) (string, []TransformResult) {
 	var results []TransformResult
 
 	// Sort by position in descending order
@@ -55,24 +64,51 @@ func InjectTypos(content string, comments []parser.Comment, p parser.Parser) (st
 
 	// Process in reverse position order
 	for _, comment := range comments {
+		// Never touch license headers, directives, shebangs or
+		// suppressed comments
+		if comment.Kind.IsProtected() || comment.Suppressed != "" {
+			continue
+		}
 
-		// Skip with 95% probability
-		if rand.Float64() > TypoProbability {
+		// Skip with 95% probability by default
+		if t.rand.Float64() > t.TypoProbability {
 			continue
 		}
 
 		// Try to inject a typo
-		newText, typoMade := injectTypoInText(comment.Text)
+		newText, typoMade := t.injectTypoInText(comment.Text)
 		if !typoMade {
 			continue
 		}
 
-		content = p.ReplaceComment(content, comment, newText)
+		newContent := p.ReplaceComment(content, comment, newText)
+		v := t.review(Edit{
+			LineNumber:  comment.LineNumber,
+			Description: "inject typo",
+			Before:      content,
+			After:       newContent,
+			Editable:    true,
+		})
+		action := "typo_injected"
+		switch v.Decision {
+		case Reject:
+			continue
+		case Replace:
+			newText = v.Text
+			newContent = p.ReplaceComment(content, comment, newText)
+			action = "replaced"
+			if newText == "" {
+				action = "removed"
+			}
+		}
+
+		content = newContent
 		results = append(results, TransformResult{
 			Original:    comment.Original,
 			Replacement: newText,
-			Action:      "typo_injected",
+			Action:      action,
 			LineNumber:  comment.LineNumber,
+			Start:       comment.Start,
 		})
 	}
 
@@ -80,11 +116,12 @@ func InjectTypos(content string, comments []parser.Comment, p parser.Parser) (st
 }
 
 // injectTypoInText attempts to add a typo to the text
-func injectTypoInText(text string) (string, bool) {
+func (t *Transformer) injectTypoInText(text string) (string, bool) {
 	lower := strings.ToLower(text)
 
-	// Try each word in the typo map
-	for correct, typo := range TypoMap {
+	// Try each word in the typo map, in a fixed order so runs repeat
+	for _, correct := range t.typoWords() {
+		typo := t.Typos[correct]
 		if idx := strings.Index(lower, correct); idx != -1 {
 			// Preserve original case of first letter
 			typoCased := typo
@@ -99,21 +136,21 @@ func injectTypoInText(text string) (string, bool) {
 	}
 
 	// If no dictionary typo found, try random character swap (20% chance)
-	if rand.Float64() < 0.2 && len(text) > 10 {
-		return randomCharSwap(text)
+	if t.rand.Float64() < 0.2 && len(text) > 10 {
+		return t.randomCharSwap(text)
 	}
 
 	return text, false
 }
 
 // randomCharSwap swaps two adjacent characters
-func randomCharSwap(text string) (string, bool) {
+func (t *Transformer) randomCharSwap(text string) (string, bool) {
 	runes := []rune(text)
 
 	// Find a good position (in the middle of a word)
 	attempts := 0
 	for attempts < 5 {
-		pos := rand.Intn(len(runes) - 1)
+		pos := t.rand.Intn(len(runes) - 1)
 		if isSwappable(runes[pos]) && isSwappable(runes[pos+1]) {
 			runes[pos], runes[pos+1] = runes[pos+1], runes[pos]
 			return string(runes), true
@@ -0,0 +1,183 @@
+package verify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"deaiify/internal/parser"
+)
+
+// Options lists the code edits a language's transformations may make
+type Options struct {
+	TrailingCommas string // Closers before which a trailing comma is optional, e.g. ")]}"
+	Placeholder    string // Statement that may replace a docstring that was the only statement of its block, e.g. "pass"
+}
+
+// Mismatch describes the first code token that differs between two versions
+type Mismatch struct {
+	Line   int    // Line in the original content
+	Before string // Original token text, empty if code was added
+	After  string // New token text, empty if code was removed
+	Break  bool   // Only the line break before the token changed
+}
+
+func (m *Mismatch) Error() string {
+	switch {
+	case m.Break:
+		return fmt.Sprintf("line %d: line break before %q was added or removed", m.Line, m.Before)
+	case m.Before == "":
+		return fmt.Sprintf("line %d: code %q was added", m.Line, m.After)
+	case m.After == "":
+		return fmt.Sprintf("line %d: code %q was removed", m.Line, m.Before)
+	}
+	return fmt.Sprintf("line %d: code %q became %q", m.Line, m.Before, m.After)
+}
+
+// Equivalent checks that after differs from before only in comments and
+// whitespace. It tokenizes both versions with p and compares every token
+// that is not a comment or docstring, returning a *Mismatch for the first
+// difference.
+func Equivalent(p parser.Parser, before, after string, opts Options) error {
+	want, slots := codeTokens(p, before, opts)
+	got, _ := codeTokens(p, after, opts)
+
+	i, j := 0, 0
+	for i < len(want) || j < len(got) {
+		// A docstring that was a block's only statement may have become a
+		// placeholder in the same place, on the same line as it was
+		if j < len(got) && isPlaceholder(got[j], opts) {
+			if brk, ok := slots[i]; ok && got[j].breakBefore == brk && (j+1 < len(got) && i < len(want) && same(want[i], got[j+1]) || j+1 == len(got) && i == len(want)) {
+				delete(slots, i)
+				j++
+				continue
+			}
+		}
+		switch {
+		case j == len(got):
+			return &Mismatch{Line: want[i].line, Before: want[i].text}
+		case i == len(want):
+			return &Mismatch{Line: lineOf(before, len(before)), After: got[j].text}
+		case !same(want[i], got[j]):
+			return &Mismatch{Line: want[i].line, Before: want[i].text, After: got[j].text}
+		case want[i].breakBefore != got[j].breakBefore:
+			// Line breaks end statements in Python, shells and JS
+			return &Mismatch{Line: want[i].line, Before: want[i].text, After: want[i].text, Break: true}
+		}
+		i++
+		j++
+	}
+	return nil
+}
+
+// same reports whether two code tokens have the same kind and text
+func same(a, b codeToken) bool {
+	return a.kind == b.kind && a.text == b.text
+}
+
+// isPlaceholder reports whether tok is the placeholder statement of opts
+func isPlaceholder(tok codeToken, opts Options) bool {
+	return opts.Placeholder != "" && tok.kind == parser.TokenCode && tok.text == opts.Placeholder
+}
+
+// codeToken is a significant token with its text resolved
+type codeToken struct {
+	kind        parser.TokenKind
+	text        string
+	line        int
+	start       int
+	breakBefore bool // A line break separates it from the previous code token
+}
+
+// codeTokens returns the tokens in content that are not comments,
+// docstrings or optional trailing commas. With a placeholder in opts it
+// also returns the places a placeholder may stand in for a docstring:
+// the index of the code token that followed it, mapped to whether a line
+// break came before the docstring.
+func codeTokens(p parser.Parser, content string, opts Options) ([]codeToken, map[int]bool) {
+	comments := make(map[int]int)
+	for _, c := range p.Parse(content).Comments {
+		comments[c.Start] = c.End
+	}
+
+	tokens := p.Tokenize(content)
+	var code []codeToken
+	var docstrings []parser.Token
+	prevEnd := 0
+	for i, tok := range tokens {
+		if tok.Kind == parser.TokenComment {
+			continue
+		}
+		if comments[tok.Start] == tok.End {
+			docstrings = append(docstrings, tok)
+			continue
+		}
+		text := tok.Text(content)
+		if text == "," && opts.TrailingCommas != "" && closes(content, tokens[i+1:], opts.TrailingCommas) {
+			continue
+		}
+		code = append(code, codeToken{
+			kind:        tok.Kind,
+			text:        text,
+			line:        lineOf(content, tok.Start),
+			start:       tok.Start,
+			breakBefore: len(code) > 0 && strings.Contains(content[prevEnd:tok.Start], "\n"),
+		})
+		prevEnd = tok.End
+	}
+
+	slots := make(map[int]bool)
+	if opts.Placeholder == "" {
+		return code, slots
+	}
+	for _, doc := range docstrings {
+		// The code tokens around the docstring
+		next := sort.Search(len(code), func(k int) bool { return code[k].start > doc.Start })
+		if next == 0 {
+			// A module docstring is never the only statement of a block
+			continue
+		}
+		prev := code[next-1]
+		if onlyStatement(content, doc, prev, code[next:]) {
+			slots[next] = strings.Contains(content[prev.start:doc.Start], "\n")
+		}
+	}
+	return code, slots
+}
+
+// onlyStatement reports whether the docstring doc is the only statement
+// of its block: no code follows it before the block is dedented. prev is
+// the code token before it and rest the code tokens after it.
+func onlyStatement(content string, doc parser.Token, prev codeToken, rest []codeToken) bool {
+	indent := lineIndent(content, doc.Start)
+	if lineOf(content, prev.start) == lineOf(content, doc.Start) {
+		// def f(): """docstring""" has a body deeper than its header
+		indent++
+	}
+	if len(rest) == 0 {
+		return true
+	}
+	return lineOf(content, rest[0].start) > lineOf(content, doc.Start) && lineIndent(content, rest[0].start) < indent
+}
+
+// closes reports whether the next significant token is one of closers
+func closes(content string, rest []parser.Token, closers string) bool {
+	for _, tok := range rest {
+		if tok.Kind == parser.TokenComment {
+			continue
+		}
+		text := tok.Text(content)
+		return tok.Kind == parser.TokenCode && len(text) == 1 && strings.Contains(closers, text)
+	}
+	return false
+}
+
+func lineOf(content string, pos int) int {
+	return strings.Count(content[:pos], "\n") + 1
+}
+
+// lineIndent returns the indentation width of the line containing pos
+func lineIndent(content string, pos int) int {
+	line := content[strings.LastIndexByte(content[:pos], '\n')+1:]
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
@@ -0,0 +1,187 @@
+package verify
+
+import (
+	"errors"
+	"testing"
+
+	"deaiify/internal/parser"
+)
+
+var pyOptions = Options{TrailingCommas: "]}", Placeholder: "pass"}
+
+func TestEquivalentPython(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		ok     bool
+	}{
+		{
+			name:   "comment reworded",
+			before: "x = 1  # Set x to one\n",
+			after:  "x = 1  # x is one\n",
+			ok:     true,
+		},
+		{
+			name:   "only docstring becomes pass",
+			before: "def f():\n    \"\"\"Do the thing.\"\"\"\n\nx = 1\n",
+			after:  "def f():\n    pass\n\nx = 1\n",
+			ok:     true,
+		},
+		{
+			name:   "only docstring at end of file becomes pass",
+			before: "class A:\n    def f(self):\n        \"\"\"Do the thing.\"\"\"\n",
+			after:  "class A:\n    def f(self):\n        pass\n",
+			ok:     true,
+		},
+		{
+			name:   "one-line docstring becomes pass",
+			before: "def f(): \"\"\"Do the thing.\"\"\"\nx = 1\n",
+			after:  "def f(): pass\nx = 1\n",
+			ok:     true,
+		},
+		{
+			name:   "docstring removed before code",
+			before: "def f():\n    \"\"\"Do the thing.\"\"\"\n    g()\n",
+			after:  "def f():\n    g()\n",
+			ok:     true,
+		},
+		{
+			name:   "pass added before code",
+			before: "def f():\n    \"\"\"Do the thing.\"\"\"\n    g(i)\n",
+			after:  "def f():\n    pass\n    g(i)\n",
+		},
+		{
+			name:   "pass added after code",
+			before: "def f():\n    g(i)\n",
+			after:  "def f():\n    g(i) pass\n",
+		},
+		{
+			name:   "pass added without a docstring",
+			before: "for i in x:\n    g(i)\n",
+			after:  "for i in x:\n    pass\n    g(i)\n",
+		},
+		{
+			name:   "pass moved onto the header line",
+			before: "def f():\n    \"\"\"Do the thing.\"\"\"\n",
+			after:  "def f(): pass\n",
+		},
+		{
+			name:   "module docstring becomes pass",
+			before: "\"\"\"Module.\"\"\"\nx = 1\n",
+			after:  "pass\nx = 1\n",
+		},
+		{
+			name:   "trailing comma removed",
+			before: "x = [\n    1,\n    2,\n]\n",
+			after:  "x = [\n    1,\n    2\n]\n",
+			ok:     true,
+		},
+		{
+			name:   "tuple comma removed",
+			before: "x = (1,)\n",
+			after:  "x = (1)\n",
+		},
+		{
+			name:   "line break added",
+			before: "x = 1; y = 2\n",
+			after:  "x = 1;\ny = 2\n",
+		},
+		{
+			name:   "line break removed",
+			before: "x = 1\ny = 2\n",
+			after:  "x = 1 y = 2\n",
+		},
+		{
+			name:   "code changed",
+			before: "x = 1\n",
+			after:  "x = 2\n",
+		},
+		{
+			name:   "string changed",
+			before: "x = \"a\"\n",
+			after:  "x = \"b\"\n",
+		},
+		{
+			name:   "code removed",
+			before: "x = 1\ny = 2\n",
+			after:  "x = 1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Equivalent(parser.NewPythonParser(), tt.before, tt.after, pyOptions)
+			if tt.ok && err != nil {
+				t.Errorf("Equivalent() = %v, want nil", err)
+			}
+			var m *Mismatch
+			if !tt.ok && !errors.As(err, &m) {
+				t.Errorf("Equivalent() = %v, want a *Mismatch", err)
+			}
+		})
+	}
+}
+
+func TestEquivalentJavaScript(t *testing.T) {
+	opts := Options{TrailingCommas: ")]}"}
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		ok     bool
+	}{
+		{
+			name:   "comment reworded",
+			before: "// This function adds\nfunction add(a, b) { return a + b }\n",
+			after:  "// adds\nfunction add(a, b) { return a + b }\n",
+			ok:     true,
+		},
+		{
+			name:   "trailing comma removed",
+			before: "f(a, b,)\n",
+			after:  "f(a, b)\n",
+			ok:     true,
+		},
+		{
+			name:   "comma between arguments removed",
+			before: "f(a, b)\n",
+			after:  "f(a b)\n",
+		},
+		{
+			name:   "line break after return",
+			before: "return x\n",
+			after:  "return\nx\n",
+		},
+		{
+			name:   "comment marker inside a string",
+			before: "s = \"// keep\"\n",
+			after:  "s = \"\"\n",
+		},
+		{
+			name:   "no placeholder outside Python",
+			before: "f()\n",
+			after:  "f()\npass\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Equivalent(parser.NewJavaScriptParser(), tt.before, tt.after, opts)
+			if tt.ok && err != nil {
+				t.Errorf("Equivalent() = %v, want nil", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("Equivalent() = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestMismatchBreak(t *testing.T) {
+	err := Equivalent(parser.NewPythonParser(), "x = 1; y = 2\n", "x = 1;\ny = 2\n", pyOptions)
+	var m *Mismatch
+	if !errors.As(err, &m) || !m.Break || m.Before != "y" {
+		t.Errorf("Equivalent() = %#v, want a line break mismatch before y", err)
+	}
+}
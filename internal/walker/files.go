@@ -4,19 +4,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-)
 
-// SupportedExtensions defines the file extensions we process
-var SupportedExtensions = map[string]bool{
-	".ts":  true,
-	".tsx": true,
-	".js":  true,
-	".jsx": true,
-	".py":  true,
-	".go":  true,
-}
+	"deaiify/internal/lang"
+)
 
-// IgnorePatterns defines directories to skip
+// IgnorePatterns are the default names of files and directories to skip
 var IgnorePatterns = []string{
 	".git",
 	"node_modules",
@@ -28,16 +20,23 @@ var IgnorePatterns = []string{
 	".next",
 	"coverage",
 	".cache",
+	".deaiify",
 }
 
 // FileInfo holds information about a file to process
 type FileInfo struct {
 	Path string
 	Ext  string
+	Lang *lang.Language
 }
 
-// Walk traverses the given path and returns all supported files
-func Walk(root string) ([]FileInfo, error) {
+// IgnoreFunc returns the patterns to skip inside dir. Patterns are
+// filepath.Match globs matched against file and directory names.
+type IgnoreFunc func(dir string) ([]string, error)
+
+// Walk traverses the given path and returns all supported files. A nil
+// ignore skips IgnorePatterns everywhere.
+func Walk(root string, ignore IgnoreFunc) ([]FileInfo, error) {
 	var files []FileInfo
 
 	info, err := os.Stat(root)
@@ -47,9 +46,8 @@ func Walk(root string) ([]FileInfo, error) {
 
 	// Single file
 	if !info.IsDir() {
-		ext := strings.ToLower(filepath.Ext(root))
-		if SupportedExtensions[ext] {
-			files = append(files, FileInfo{Path: root, Ext: ext})
+		if l := lang.ForPath(root); l != nil {
+			files = append(files, FileInfo{Path: root, Ext: strings.ToLower(filepath.Ext(root)), Lang: l})
 		}
 		return files, nil
 	}
@@ -60,21 +58,32 @@ func Walk(root string) ([]FileInfo, error) {
 			return err
 		}
 
-		// Skip ignored directories
-		if info.IsDir() {
-			name := info.Name()
-			for _, pattern := range IgnorePatterns {
-				if name == pattern {
-					return filepath.SkipDir
-				}
+		// Skip ignored files and directories, but never the root itself
+		if path != root {
+			skip, err := ignored(path, ignore)
+			if err != nil {
+				return err
 			}
+			if skip && info.IsDir() {
+				return filepath.SkipDir
+			}
+			if skip {
+				return nil
+			}
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		// Skip symlinks: the target is either walked itself or lies
+		// outside the tree
+		if info.Mode()&os.ModeSymlink != 0 {
 			return nil
 		}
 
-		// Check extension
-		ext := strings.ToLower(filepath.Ext(path))
-		if SupportedExtensions[ext] {
-			files = append(files, FileInfo{Path: path, Ext: ext})
+		// Check extension or name
+		if l := lang.ForPath(path); l != nil {
+			files = append(files, FileInfo{Path: path, Ext: strings.ToLower(filepath.Ext(path)), Lang: l})
 		}
 
 		return nil
@@ -83,17 +92,20 @@ func Walk(root string) ([]FileInfo, error) {
 	return files, err
 }
 
-// IsJavaScript returns true if the extension is JS/TS
-func (f FileInfo) IsJavaScript() bool {
-	return f.Ext == ".js" || f.Ext == ".jsx" || f.Ext == ".ts" || f.Ext == ".tsx"
-}
-
-// IsPython returns true if the extension is Python
-func (f FileInfo) IsPython() bool {
-	return f.Ext == ".py"
-}
-
-// IsGo returns true if the extension is Go
-func (f FileInfo) IsGo() bool {
-	return f.Ext == ".go"
+// ignored reports whether path's name matches a pattern for its directory
+func ignored(path string, ignore IgnoreFunc) (bool, error) {
+	patterns := IgnorePatterns
+	if ignore != nil {
+		var err error
+		if patterns, err = ignore(filepath.Dir(path)); err != nil {
+			return false, err
+		}
+	}
+	name := filepath.Base(path)
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true, nil
+		}
+	}
+	return false, nil
 }